@@ -1,16 +1,24 @@
 package main
 
 import (
-	"encoding/gob"
-	"encoding/json"
+	"errors"
 	"flag"
+	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 
 	"github.com/jdkato/gnols/internal/gno"
 )
 
-var buildOutput = "internal/stdlib/stdlib"
+// The indexes are written in the source tree; internal/stdlib isn't imported,
+// so an outdated or broken embedded index doesn't prevent generating it again.
+var (
+	stdlibDir   = "internal/stdlib"
+	buildOutput = filepath.Join(stdlibDir, "stdlib")
+	versionsDir = filepath.Join(stdlibDir, "versions")
+)
 
 func main() {
 	hd, err := os.UserHomeDir()
@@ -28,8 +36,30 @@ func main() {
 		"Format to save the symbols in; 'gob' or 'json'.",
 	)
 
+	gnoBin := flag.String(
+		"gno", "gno",
+		"Path to the gno binary built from the repository, to detect its version.",
+	)
+
+	version := flag.String(
+		"version", "",
+		"Gno version of the repository; detected with 'gno version' if empty.",
+	)
+
+	latest := flag.Bool(
+		"latest", true,
+		"Replace the index of the latest version. The previous one is kept with the versioned indexes.",
+	)
+
 	flag.Parse()
 
+	if *version == "" {
+		*version, err = gno.BinaryVersion(*gnoBin)
+		if err != nil {
+			panic(fmt.Errorf("can't detect the gno version, use -version: %w", err))
+		}
+	}
+
 	dirs := [...]string{
 		filepath.Join(*rootDir, "examples"),
 		filepath.Join(*rootDir, "gnovm/stdlibs"),
@@ -43,39 +73,58 @@ func main() {
 		}
 		pkgs = append(pkgs, dirPkgs...)
 	}
+	for i := range pkgs {
+		// the directories are those of the machine generating the index
+		pkgs[i].Dir = ""
+	}
 
-	saveSymbols(pkgs, *storageFormat)
-}
-
-func saveSymbols(pkgs []gno.Package, format string) {
-	switch format {
-	case "gob":
-		toGob(pkgs)
-	case "json":
-		toJSON(pkgs)
+	output := buildOutput
+	if !*latest {
+		output = filepath.Join(versionsDir, gno.IndexName(*version))
+	} else {
+		keepPrevious(*version, *storageFormat)
 	}
+	saveSymbols(gno.NewIndexFile(*version, pkgs), output, *storageFormat)
 }
 
-func toJSON(pkgs []gno.Package) {
-	found, err := json.MarshalIndent(pkgs, "", " ")
+// keepPrevious moves the index of the latest version, read from the disk, to
+// the versioned indexes, unless it's an index of version or of an unknown
+// version. An index which can't be read is replaced.
+func keepPrevious(version, format string) {
+	filename := buildOutput + "." + format
+	prev, err := gno.ReadIndexFile(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
 	if err != nil {
-		panic(err)
+		fmt.Fprintf(os.Stderr, "replacing %s: %v\n", filename, err)
+		return
 	}
-
-	err = os.WriteFile(buildOutput+".json", found, os.ModePerm)
+	if prev.Version == "" || prev.Version == version {
+		return
+	}
+	err = os.Rename(filename, filepath.Join(versionsDir, gno.IndexName(prev.Version)+"."+format))
 	if err != nil {
 		panic(err)
 	}
 }
 
-func toGob(pkgs []gno.Package) {
-	dataFile, err := os.Create(buildOutput + ".gob")
+func saveSymbols(index *gno.IndexFile, output, format string) {
+	switch format {
+	case "gob":
+		toFile(output+".gob", index.WriteGob)
+	case "json":
+		toFile(output+".json", index.WriteJSON)
+	}
+}
+
+func toFile(name string, write func(io.Writer) error) {
+	dataFile, err := os.Create(name)
 	if err != nil {
 		panic(err)
 	}
-	dataEncoder := gob.NewEncoder(dataFile)
 
-	err = dataEncoder.Encode(pkgs)
+	err = write(dataFile)
 	if err != nil {
 		panic(err)
 	}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/jdkato/gnols/internal/dap"
 	"github.com/jdkato/gnols/internal/handler"
 	"go.lsp.dev/jsonrpc2"
 )
@@ -18,6 +19,14 @@ func main1() (ret int) {
 			ret = 2
 		}
 	}()
+	if len(os.Args) > 1 && os.Args[1] == "dap" {
+		// Debug Adapter Protocol mode
+		if err := dap.NewServer(stdrwc{}).Serve(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			ret = 1
+		}
+		return
+	}
 	conn := jsonrpc2.NewConn(jsonrpc2.NewStream(stdrwc{}))
 
 	handler := handler.NewHandler(conn)
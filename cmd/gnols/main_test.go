@@ -59,6 +59,12 @@ func TestScripts(t *testing.T) {
 			// Listen to server notifications
 			var notifyNum atomic.Uint32
 			clientConn.Go(ctx, func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+				if _, ok := req.(*jsonrpc2.Call); ok {
+					// write server requests into $WORK/output/{method}.json, they
+					// are sent asynchronously so they can't be numbered.
+					filename := strings.ReplaceAll(req.Method(), "/", "_") + ".json"
+					return writeJSON(env, filename, req)
+				}
 				// write server notifications into $WORK/output/notify{++notifyNum}.json
 				filename := fmt.Sprintf("notify%d.json", notifyNum.Add(1))
 				return writeJSON(env, filename, req)
@@ -5,7 +5,7 @@ go 1.22
 toolchain go1.22.4
 
 require (
-	github.com/davecgh/go-spew v1.1.1
+	github.com/google/go-dap v0.12.0
 	github.com/orcaman/concurrent-map/v2 v2.0.1
 	github.com/rogpeppe/go-internal v1.12.0
 	github.com/sourcegraph/go-diff v0.7.0
@@ -13,10 +13,12 @@ require (
 	go.lsp.dev/jsonrpc2 v0.10.0
 	go.lsp.dev/protocol v0.12.0
 	go.lsp.dev/uri v0.3.0
+	golang.org/x/mod v0.18.0
 	golang.org/x/tools v0.22.0
 )
 
 require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/segmentio/asm v1.1.3 // indirect
@@ -0,0 +1,192 @@
+package dap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// prompt is printed by the gno debugger when it waits for a command.
+const prompt = "dbg> "
+
+var (
+	// reStop matches the location printed when the debugger stops:
+	// > main.main() main/main.gno:9:2
+	reStop = regexp.MustCompile(`(?m)^> (\S+) (\S+):(\d+):(\d+)`)
+	// reBreakpoint matches the output of the break command:
+	// Breakpoint 0 at main main/main.gno:9:1
+	reBreakpoint = regexp.MustCompile(`Breakpoint (\d+) at \S+ (\S+):(\d+):(\d+)`)
+	// reFrame matches a frame of the stack command:
+	// 0	in main.f
+	//	at main/main.gno:5:2
+	reFrame = regexp.MustCompile(`(?m)^(\d+)\s+in (\S+)\s*\n\s+at (\S+):(\d+):(\d+)`)
+)
+
+// Location is a position in a Gno source file.
+type Location struct {
+	Func   string
+	File   string
+	Line   int
+	Column int
+}
+
+// debugger drives a `gno run -debug` or `gno test -debug` process through its
+// interactive prompt.
+type debugger struct {
+	// dir is used to resolve the relative file names printed by the debugger.
+	dir string
+
+	mu     sync.Mutex
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	exited bool
+
+	stopOnce sync.Once
+	stopErr  error
+}
+
+// launch starts the gno debugger with args and waits for its first prompt.
+// The output printed before the prompt is returned.
+func launch(gnoBin, dir string, args ...string) (*debugger, string, error) {
+	cmd := exec.Command(gnoBin, args...) //nolint:gosec
+	cmd.Dir = dir
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, "", err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, "", err
+	}
+	cmd.Stderr = cmd.Stdout
+	if err := cmd.Start(); err != nil {
+		return nil, "", fmt.Errorf("running '%s %s': %w", gnoBin, strings.Join(args, " "), err)
+	}
+	d := &debugger{
+		dir:    dir,
+		cmd:    cmd,
+		stdin:  stdin,
+		stdout: bufio.NewReader(stdout),
+	}
+	out := d.readUntilPrompt()
+	if d.exited {
+		return nil, out, fmt.Errorf("gno debugger exited: %s", out)
+	}
+	return d, out, nil
+}
+
+// exec sends command to the debugger and returns its output. exited is true
+// if the debugged program terminated.
+func (d *debugger) exec(command string) (out string, exited bool, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.exited {
+		return "", true, nil
+	}
+	if _, err := io.WriteString(d.stdin, command+"\n"); err != nil {
+		return "", false, err
+	}
+	out = d.readUntilPrompt()
+	return out, d.exited, nil
+}
+
+// readUntilPrompt reads stdout until the debugger prompt or the end of the
+// process.
+func (d *debugger) readUntilPrompt() string {
+	var sb strings.Builder
+	for {
+		b, err := d.stdout.ReadByte()
+		if err != nil {
+			d.exited = true
+			return sb.String()
+		}
+		sb.WriteByte(b)
+		if strings.HasSuffix(sb.String(), prompt) {
+			return strings.TrimSuffix(sb.String(), prompt)
+		}
+	}
+}
+
+// stop terminates the debugger and the debugged program. The process is
+// killed without waiting for the current command, which may never return if
+// the program is running. stop can be called more than once.
+func (d *debugger) stop() error {
+	d.stopOnce.Do(func() {
+		d.stdin.Close()
+		d.cmd.Process.Kill() //nolint:errcheck
+		err := d.cmd.Wait()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			// the process is killed, nothing to report.
+			err = nil
+		}
+		d.stopErr = err
+	})
+	return d.stopErr
+}
+
+// resolve turns a file name printed by the debugger into an absolute path.
+func (d *debugger) resolve(file string) string {
+	if filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(d.dir, filepath.Base(file))
+}
+
+// parseStop returns the location where the program stopped, found in the
+// output of a continue or step command.
+func parseStop(out string) (Location, bool) {
+	m := reStop.FindStringSubmatch(out)
+	if m == nil {
+		return Location{}, false
+	}
+	return newLocation(m[1], m[2], m[3], m[4]), true
+}
+
+// parseBreakpoint returns the id and the location of the breakpoint created by
+// a break command.
+func parseBreakpoint(out string) (int, Location, bool) {
+	m := reBreakpoint.FindStringSubmatch(out)
+	if m == nil {
+		return 0, Location{}, false
+	}
+	id, _ := strconv.Atoi(m[1])
+	return id, newLocation("", m[2], m[3], m[4]), true
+}
+
+// parseStack returns the frames found in the output of a stack command.
+func parseStack(out string) []Location {
+	var frames []Location
+	for _, m := range reFrame.FindAllStringSubmatch(out, -1) {
+		frames = append(frames, newLocation(m[2], m[3], m[4], m[5]))
+	}
+	return frames
+}
+
+// programOutput returns out without the lines printed by the debugger when it
+// stops, so the rest can be forwarded as the program output.
+func programOutput(out string) string {
+	if loc := reStop.FindStringIndex(out); loc != nil {
+		return out[:loc[0]]
+	}
+	return out
+}
+
+func newLocation(fn, file, line, col string) Location {
+	l, _ := strconv.Atoi(line)
+	c, _ := strconv.Atoi(col)
+	return Location{
+		Func:   strings.TrimSuffix(fn, "()"),
+		File:   file,
+		Line:   l,
+		Column: c,
+	}
+}
@@ -0,0 +1,64 @@
+package dap
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStop(t *testing.T) {
+	loc, ok := parseStop("hello\n> main.main() main/main.gno:9:2\n     8:\n=>   9:\tx := 1\n")
+
+	require.True(t, ok)
+	assert.Equal(t, Location{Func: "main.main", File: "main/main.gno", Line: 9, Column: 2}, loc)
+
+	_, ok = parseStop("Command failed: unknown command\n")
+
+	assert.False(t, ok)
+}
+
+func TestParseBreakpoint(t *testing.T) {
+	id, loc, ok := parseBreakpoint("Breakpoint 2 at main main/main.gno:7:1\n")
+
+	require.True(t, ok)
+	assert.Equal(t, 2, id)
+	assert.Equal(t, Location{File: "main/main.gno", Line: 7, Column: 1}, loc)
+}
+
+func TestParseStack(t *testing.T) {
+	frames := parseStack("0\tin main.f\n\tat main/main.gno:5:2\n1\tin main.main\n\tat main/main.gno:10:2\n")
+
+	assert.Equal(t, []Location{
+		{Func: "main.f", File: "main/main.gno", Line: 5, Column: 2},
+		{Func: "main.main", File: "main/main.gno", Line: 10, Column: 2},
+	}, frames)
+}
+
+func TestProgramOutput(t *testing.T) {
+	assert.Equal(t, "hello\n", programOutput("hello\n> main.main() main/main.gno:9:2\n"))
+	assert.Equal(t, "hello\n", programOutput("hello\n"))
+}
+
+func TestLocalNames(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "main.gno")
+	err := os.WriteFile(file, []byte(`package main
+
+func f(a int, b string) (r int) {
+	x := a
+	var y, z int
+	for i, v := range b {
+		_ = func() { w := 1 }
+	}
+	after := 2
+	return x + y + z
+}
+`), os.ModePerm)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"a", "b", "r", "x", "y", "z", "i", "v"}, localNames(file, 8))
+	assert.Equal(t, []string{"a", "b", "r", "x", "y", "z", "i", "v", "after"}, localNames(file, 10))
+	assert.Empty(t, localNames(file, 1))
+}
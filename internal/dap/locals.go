@@ -0,0 +1,89 @@
+package dap
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+)
+
+// localNames returns the names of the parameters and of the local variables
+// declared before line in the function enclosing line.
+//
+// The gno debugger has no command to list the variables of a frame, so we
+// find them in the source and evaluate them one by one.
+func localNames(file string, line int) []string {
+	fset := token.NewFileSet()
+	f, _ := parser.ParseFile(fset, file, nil, 0)
+	if f == nil {
+		return nil
+	}
+	var fn *ast.FuncDecl
+	for _, d := range f.Decls {
+		d, ok := d.(*ast.FuncDecl)
+		if !ok || d.Body == nil {
+			continue
+		}
+		if fset.Position(d.Pos()).Line <= line && line <= fset.Position(d.End()).Line {
+			fn = d
+			break
+		}
+	}
+	if fn == nil {
+		return nil
+	}
+
+	var (
+		names []string
+		seen  = map[string]bool{}
+		add   = func(ids ...*ast.Ident) {
+			for _, id := range ids {
+				if id == nil || id.Name == "_" || seen[id.Name] {
+					continue
+				}
+				if fset.Position(id.Pos()).Line > line {
+					continue
+				}
+				seen[id.Name] = true
+				names = append(names, id.Name)
+			}
+		}
+		addFields = func(fl *ast.FieldList) {
+			if fl == nil {
+				return
+			}
+			for _, f := range fl.List {
+				add(f.Names...)
+			}
+		}
+	)
+	if fn.Recv != nil {
+		addFields(fn.Recv)
+	}
+	addFields(fn.Type.Params)
+	addFields(fn.Type.Results)
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			if n.Tok == token.DEFINE {
+				for _, lhs := range n.Lhs {
+					if id, ok := lhs.(*ast.Ident); ok {
+						add(id)
+					}
+				}
+			}
+		case *ast.ValueSpec:
+			add(n.Names...)
+		case *ast.RangeStmt:
+			if n.Tok == token.DEFINE {
+				k, _ := n.Key.(*ast.Ident)
+				v, _ := n.Value.(*ast.Ident)
+				add(k, v)
+			}
+		case *ast.FuncLit:
+			// variables of closures aren't visible from the enclosing function
+			return false
+		}
+		return true
+	})
+	return names
+}
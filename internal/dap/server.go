@@ -0,0 +1,504 @@
+// Package dap implements a Debug Adapter Protocol server on top of the gno
+// debugger (`gno run -debug` and `gno test -debug`).
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/google/go-dap"
+
+	"github.com/jdkato/gnols/internal/gno"
+)
+
+// threadID is the id of the only thread exposed to the client, Gno programs
+// being single-threaded.
+const threadID = 1
+
+// errRunning is returned by the requests which need the program to be
+// stopped.
+var errRunning = errors.New("program is running")
+
+// LaunchArgs are the arguments of the launch request.
+type LaunchArgs struct {
+	// Mode is either "run" to debug a main package, or "test" to debug tests.
+	Mode string `json:"mode"`
+	// Program is the file to run, or the package directory to test.
+	Program string `json:"program"`
+	// Test is the name of the test to debug in "test" mode.
+	Test string `json:"test,omitempty"`
+	// StopOnEntry stops the program before its first statement.
+	StopOnEntry bool `json:"stopOnEntry,omitempty"`
+	// Gno is the path to the gno binary, found in PATH if empty.
+	Gno string `json:"gno,omitempty"`
+	// Root is the path to the gno repository.
+	Root string `json:"root,omitempty"`
+}
+
+// Server is a Debug Adapter Protocol server.
+type Server struct {
+	rw     io.ReadWriter
+	reader *bufio.Reader
+
+	sendMu sync.Mutex
+	seq    int
+
+	dbg         *debugger
+	stopOnEntry bool
+	// stateMu is held while the debugger runs a sequence of commands, like
+	// moving to a frame and printing an expression, so a resume can't be
+	// interleaved with it.
+	stateMu sync.Mutex
+	// running is true from the resume of the program until it stops.
+	running bool
+	// breakpoints contains the ids of the breakpoints per source file.
+	breakpoints map[string][]int
+}
+
+func NewServer(rw io.ReadWriter) *Server {
+	return &Server{
+		rw:          rw,
+		reader:      bufio.NewReader(rw),
+		breakpoints: make(map[string][]int),
+	}
+}
+
+// Serve handles the client requests until the session is disconnected or rw
+// is closed.
+func (s *Server) Serve() error {
+	for {
+		msg, err := dap.ReadProtocolMessage(s.reader)
+		if err != nil {
+			var fieldErr *dap.DecodeProtocolMessageFieldError
+			if errors.As(err, &fieldErr) {
+				// unsupported request, skip it
+				slog.Error("dap", "err", err)
+				continue
+			}
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		req, ok := msg.(dap.RequestMessage)
+		if !ok {
+			continue
+		}
+		slog.Info("dap", "command", req.GetRequest().Command)
+		if err := s.handle(req); err != nil {
+			s.sendErr(req.GetRequest(), err)
+		}
+		if _, ok := msg.(*dap.DisconnectRequest); ok {
+			return nil
+		}
+	}
+}
+
+func (s *Server) handle(msg dap.RequestMessage) error {
+	switch req := msg.(type) {
+	case *dap.InitializeRequest:
+		s.send(&dap.InitializeResponse{
+			Response: s.response(&req.Request),
+			Body: dap.Capabilities{
+				SupportsConfigurationDoneRequest: true,
+				SupportsEvaluateForHovers:        true,
+			},
+		})
+	case *dap.LaunchRequest:
+		return s.launch(req)
+	case *dap.SetBreakpointsRequest:
+		return s.setBreakpoints(req)
+	case *dap.SetExceptionBreakpointsRequest:
+		s.send(&dap.SetExceptionBreakpointsResponse{Response: s.response(&req.Request)})
+	case *dap.ConfigurationDoneRequest:
+		s.send(&dap.ConfigurationDoneResponse{Response: s.response(&req.Request)})
+		if s.stopOnEntry {
+			s.sendStopped("entry")
+		} else if err := s.setRunning(); err != nil {
+			s.sendOutput(err.Error())
+			s.sendTerminated()
+		} else {
+			go s.resume("continue", "breakpoint")
+		}
+	case *dap.ThreadsRequest:
+		s.send(&dap.ThreadsResponse{
+			Response: s.response(&req.Request),
+			Body:     dap.ThreadsResponseBody{Threads: []dap.Thread{{Id: threadID, Name: "main"}}},
+		})
+	case *dap.StackTraceRequest:
+		return s.stackTrace(req)
+	case *dap.ScopesRequest:
+		s.send(&dap.ScopesResponse{
+			Response: s.response(&req.Request),
+			Body: dap.ScopesResponseBody{Scopes: []dap.Scope{{
+				Name: "Locals",
+				// frame ids start at 0 while 0 means no variables.
+				VariablesReference: req.Arguments.FrameId + 1,
+			}}},
+		})
+	case *dap.VariablesRequest:
+		return s.variables(req)
+	case *dap.EvaluateRequest:
+		out, err := s.evaluate(req.Arguments.FrameId, req.Arguments.Expression)
+		if err != nil {
+			return err
+		}
+		s.send(&dap.EvaluateResponse{
+			Response: s.response(&req.Request),
+			Body:     dap.EvaluateResponseBody{Result: out},
+		})
+	case *dap.ContinueRequest:
+		if err := s.setRunning(); err != nil {
+			return err
+		}
+		s.send(&dap.ContinueResponse{
+			Response: s.response(&req.Request),
+			Body:     dap.ContinueResponseBody{AllThreadsContinued: true},
+		})
+		go s.resume("continue", "breakpoint")
+	case *dap.NextRequest:
+		if err := s.setRunning(); err != nil {
+			return err
+		}
+		s.send(&dap.NextResponse{Response: s.response(&req.Request)})
+		go s.resume("next", "step")
+	case *dap.StepInRequest:
+		if err := s.setRunning(); err != nil {
+			return err
+		}
+		s.send(&dap.StepInResponse{Response: s.response(&req.Request)})
+		go s.resume("step", "step")
+	case *dap.StepOutRequest:
+		if err := s.setRunning(); err != nil {
+			return err
+		}
+		s.send(&dap.StepOutResponse{Response: s.response(&req.Request)})
+		go s.resume("stepout", "step")
+	case *dap.DisconnectRequest:
+		if s.dbg != nil {
+			if err := s.dbg.stop(); err != nil {
+				return err
+			}
+		}
+		s.send(&dap.DisconnectResponse{Response: s.response(&req.Request)})
+	case *dap.TerminateRequest:
+		if s.dbg != nil {
+			if err := s.dbg.stop(); err != nil {
+				return err
+			}
+		}
+		s.send(&dap.TerminateResponse{Response: s.response(&req.Request)})
+		s.sendTerminated()
+	default:
+		return fmt.Errorf("unsupported command %q", msg.GetRequest().Command)
+	}
+	return nil
+}
+
+func (s *Server) launch(req *dap.LaunchRequest) error {
+	var args LaunchArgs
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		return fmt.Errorf("invalid launch arguments: %w", err)
+	}
+	if args.Gno == "" {
+		var err error
+		args.Gno, err = exec.LookPath("gno")
+		if err != nil {
+			return gno.ErrNoGno
+		}
+	}
+	program, err := filepath.Abs(args.Program)
+	if err != nil {
+		return err
+	}
+
+	var cmdArgs []string
+	switch args.Mode {
+	case "", "run":
+		cmdArgs = []string{"run", "-debug"}
+	case "test":
+		cmdArgs = []string{"test", "-debug", "-verbose"}
+		if args.Test != "" {
+			cmdArgs = append(cmdArgs, "-run", fmt.Sprintf("^%s$", args.Test))
+		}
+	default:
+		return fmt.Errorf("unknown launch mode %q", args.Mode)
+	}
+	if args.Root != "" {
+		cmdArgs = append(cmdArgs, "-root-dir", args.Root)
+	}
+	cmdArgs = append(cmdArgs, program)
+
+	dir := program
+	if info, err := os.Stat(program); err == nil && !info.IsDir() {
+		dir = filepath.Dir(program)
+	}
+	slog.Info("dap launch", "gno", args.Gno, "args", cmdArgs)
+	dbg, out, err := launch(args.Gno, dir, cmdArgs...)
+	if err != nil {
+		return err
+	}
+	s.dbg = dbg
+	s.stopOnEntry = args.StopOnEntry
+	s.sendOutput(out)
+	s.send(&dap.LaunchResponse{Response: s.response(&req.Request)})
+	// The client can now send the breakpoints.
+	s.send(&dap.InitializedEvent{Event: s.event("initialized")})
+	return nil
+}
+
+func (s *Server) setBreakpoints(req *dap.SetBreakpointsRequest) error {
+	if s.dbg == nil {
+		return errors.New("program not launched")
+	}
+	path := req.Arguments.Source.Path
+	for _, id := range s.breakpoints[path] {
+		if _, _, err := s.dbg.exec(fmt.Sprintf("clear %d", id)); err != nil {
+			return err
+		}
+	}
+	s.breakpoints[path] = nil
+
+	bps := make([]dap.Breakpoint, 0, len(req.Arguments.Breakpoints))
+	for _, b := range req.Arguments.Breakpoints {
+		out, _, err := s.dbg.exec(fmt.Sprintf("break %s:%d", path, b.Line))
+		if err != nil {
+			return err
+		}
+		bp := dap.Breakpoint{
+			Source: &req.Arguments.Source,
+			Line:   b.Line,
+		}
+		if id, loc, ok := parseBreakpoint(out); ok {
+			s.breakpoints[path] = append(s.breakpoints[path], id)
+			bp.Id = id
+			bp.Verified = true
+			bp.Line = loc.Line
+		} else {
+			bp.Message = strings.TrimSpace(out)
+		}
+		bps = append(bps, bp)
+	}
+	s.send(&dap.SetBreakpointsResponse{
+		Response: s.response(&req.Request),
+		Body:     dap.SetBreakpointsResponseBody{Breakpoints: bps},
+	})
+	return nil
+}
+
+// setRunning marks the program as running before it's resumed, so the frames
+// aren't queried until it stops.
+func (s *Server) setRunning() error {
+	if s.dbg == nil {
+		return errors.New("program not launched")
+	}
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	if s.running {
+		return errRunning
+	}
+	s.running = true
+	return nil
+}
+
+// lockStopped locks stateMu if the program is stopped, so the frames can be
+// queried until it's unlocked.
+func (s *Server) lockStopped() error {
+	if s.dbg == nil {
+		return errors.New("program not launched")
+	}
+	s.stateMu.Lock()
+	if s.running {
+		s.stateMu.Unlock()
+		return errRunning
+	}
+	return nil
+}
+
+// resume sends command to the debugger and waits for the program to stop or
+// to terminate.
+func (s *Server) resume(command, reason string) {
+	out, exited, err := s.dbg.exec(command)
+	// the events are sent once the requests can be handled again
+	s.stateMu.Lock()
+	s.running = false
+	s.stateMu.Unlock()
+	if err != nil {
+		s.sendOutput(err.Error())
+		s.sendTerminated()
+		return
+	}
+	s.sendOutput(programOutput(out))
+	if exited {
+		s.sendTerminated()
+		return
+	}
+	if _, ok := parseStop(out); !ok {
+		// The debugger didn't move, likely because of an invalid command.
+		s.sendOutput(out)
+	}
+	s.sendStopped(reason)
+}
+
+func (s *Server) stackTrace(req *dap.StackTraceRequest) error {
+	if err := s.lockStopped(); err != nil {
+		return err
+	}
+	defer s.stateMu.Unlock()
+	out, _, err := s.dbg.exec("stack")
+	if err != nil {
+		return err
+	}
+	frames := parseStack(out)
+	stackFrames := make([]dap.StackFrame, len(frames))
+	for i, f := range frames {
+		path := s.dbg.resolve(f.File)
+		stackFrames[i] = dap.StackFrame{
+			Id:     i,
+			Name:   f.Func,
+			Source: &dap.Source{Name: filepath.Base(path), Path: path},
+			Line:   f.Line,
+			Column: f.Column,
+		}
+	}
+	s.send(&dap.StackTraceResponse{
+		Response: s.response(&req.Request),
+		Body: dap.StackTraceResponseBody{
+			StackFrames: stackFrames,
+			TotalFrames: len(stackFrames),
+		},
+	})
+	return nil
+}
+
+func (s *Server) variables(req *dap.VariablesRequest) error {
+	frameID := req.Arguments.VariablesReference - 1
+	if err := s.lockStopped(); err != nil {
+		return err
+	}
+	defer s.stateMu.Unlock()
+	out, _, err := s.dbg.exec("stack")
+	if err != nil {
+		return err
+	}
+	frames := parseStack(out)
+	vars := []dap.Variable{}
+	if frameID >= 0 && frameID < len(frames) {
+		f := frames[frameID]
+		for _, name := range localNames(s.dbg.resolve(f.File), f.Line) {
+			value, err := s.print(frameID, name)
+			if err != nil {
+				return err
+			}
+			vars = append(vars, dap.Variable{Name: name, Value: value})
+		}
+	}
+	s.send(&dap.VariablesResponse{
+		Response: s.response(&req.Request),
+		Body:     dap.VariablesResponseBody{Variables: vars},
+	})
+	return nil
+}
+
+// evaluate prints expr in the frame frameID.
+func (s *Server) evaluate(frameID int, expr string) (string, error) {
+	if err := s.lockStopped(); err != nil {
+		return "", err
+	}
+	defer s.stateMu.Unlock()
+	return s.print(frameID, expr)
+}
+
+// print prints expr in the frame frameID, stateMu must be held.
+func (s *Server) print(frameID int, expr string) (string, error) {
+	if frameID > 0 {
+		if _, _, err := s.dbg.exec(fmt.Sprintf("up %d", frameID)); err != nil {
+			return "", err
+		}
+		defer s.dbg.exec(fmt.Sprintf("down %d", frameID)) //nolint:errcheck
+	}
+	out, _, err := s.dbg.exec("print " + expr)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+func (s *Server) sendStopped(reason string) {
+	s.send(&dap.StoppedEvent{
+		Event: s.event("stopped"),
+		Body: dap.StoppedEventBody{
+			Reason:            reason,
+			ThreadId:          threadID,
+			AllThreadsStopped: true,
+		},
+	})
+}
+
+func (s *Server) sendTerminated() {
+	s.send(&dap.TerminatedEvent{Event: s.event("terminated")})
+}
+
+func (s *Server) sendOutput(out string) {
+	if out == "" {
+		return
+	}
+	s.send(&dap.OutputEvent{
+		Event: s.event("output"),
+		Body:  dap.OutputEventBody{Category: "stdout", Output: out},
+	})
+}
+
+func (s *Server) sendErr(req *dap.Request, err error) {
+	slog.Error("dap", "command", req.Command, "err", err)
+	resp := s.response(req)
+	resp.Success = false
+	resp.Message = err.Error()
+	s.send(&dap.ErrorResponse{
+		Response: resp,
+		Body: dap.ErrorResponseBody{
+			Error: &dap.ErrorMessage{Id: 1, Format: err.Error()},
+		},
+	})
+}
+
+func (s *Server) response(req *dap.Request) dap.Response {
+	return dap.Response{
+		ProtocolMessage: dap.ProtocolMessage{Type: "response"},
+		Command:         req.Command,
+		RequestSeq:      req.Seq,
+		Success:         true,
+	}
+}
+
+func (s *Server) event(name string) dap.Event {
+	return dap.Event{
+		ProtocolMessage: dap.ProtocolMessage{Type: "event"},
+		Event:           name,
+	}
+}
+
+// send writes msg to the client, setting its sequence number.
+func (s *Server) send(msg dap.Message) {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	s.seq++
+	switch m := msg.(type) {
+	case dap.ResponseMessage:
+		m.GetResponse().Seq = s.seq
+	case dap.EventMessage:
+		m.GetEvent().Seq = s.seq
+	}
+	if err := dap.WriteProtocolMessage(s.rw, msg); err != nil {
+		slog.Error("dap send", "err", err)
+	}
+}
@@ -0,0 +1,245 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/google/go-dap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGno emulates the gno debugger prompt.
+const fakeGno = `#!/bin/sh
+printf 'Welcome to the Gnovm debugger.\ndbg> '
+while read line; do
+	case "$line" in
+	break*) printf 'Breakpoint 0 at main main/main.gno:4:2\ndbg> ' ;;
+	continue) printf 'hello\n> main.main() main/main.gno:4:2\ndbg> ' ;;
+	next) sleep 1; printf '> main.main() main/main.gno:5:2\ndbg> ' ;;
+	stack) printf '0\tin main.main\n\tat main/main.gno:4:2\ndbg> ' ;;
+	"print x") printf '(1 int)\ndbg> ' ;;
+	exit) exit 0 ;;
+	*) printf 'dbg> ' ;;
+	esac
+done
+`
+
+type pipe struct {
+	io.Reader
+	io.Writer
+}
+
+// session is a DAP session with a server driving a fake gno debugger.
+type session struct {
+	t        *testing.T
+	mainFile string
+	gnoBin   string
+	writer   io.Writer
+	msgs     chan dap.Message
+	seq      int
+	done     chan error
+}
+
+// newSession starts a server, the gno binary being the shell script gnoScript.
+func newSession(t *testing.T, gnoScript string) *session {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("requires sh")
+	}
+	dir := t.TempDir()
+	gnoBin := filepath.Join(dir, "gno")
+	require.NoError(t, os.WriteFile(gnoBin, []byte(gnoScript), 0o755)) //nolint:gosec
+	mainFile := filepath.Join(dir, "main.gno")
+	require.NoError(t, os.WriteFile(mainFile, []byte("package main\n\nfunc main() {\n\tx := 1\n\tprintln(x)\n}\n"), os.ModePerm))
+
+	var (
+		clientRead, serverWrite = io.Pipe()
+		serverRead, clientWrite = io.Pipe()
+		server                  = NewServer(pipe{serverRead, serverWrite})
+	)
+	s := &session{
+		t:        t,
+		mainFile: mainFile,
+		gnoBin:   gnoBin,
+		writer:   clientWrite,
+		msgs:     make(chan dap.Message, 100),
+		done:     make(chan error),
+	}
+	go func() { s.done <- server.Serve() }()
+	// the messages are read continuously, so the server never blocks on
+	// sending events while a request is sent.
+	go func() {
+		reader := bufio.NewReader(clientRead)
+		for {
+			msg, err := dap.ReadProtocolMessage(reader)
+			if err != nil {
+				close(s.msgs)
+				return
+			}
+			s.msgs <- msg
+		}
+	}()
+	return s
+}
+
+func (s *session) send(msg dap.RequestMessage) {
+	s.seq++
+	msg.GetRequest().Seq = s.seq
+	msg.GetRequest().Type = "request"
+	require.NoError(s.t, dap.WriteProtocolMessage(s.writer, msg))
+}
+
+// recv returns the next message, skipping output events.
+func (s *session) recv() dap.Message {
+	for {
+		select {
+		case msg, ok := <-s.msgs:
+			if !ok {
+				s.t.Fatal("connection closed")
+			}
+			if _, ok := msg.(*dap.OutputEvent); !ok {
+				return msg
+			}
+		case <-time.After(5 * time.Second):
+			s.t.Fatal("no message received")
+		}
+	}
+}
+
+// launch launches the program and waits for the initialized event.
+func (s *session) launch() {
+	args, _ := json.Marshal(LaunchArgs{Program: s.mainFile, Gno: s.gnoBin})
+	s.send(&dap.LaunchRequest{Request: dap.Request{Command: "launch"}, Arguments: args})
+	assert.IsType(s.t, &dap.LaunchResponse{}, s.recv())
+	assert.IsType(s.t, &dap.InitializedEvent{}, s.recv())
+}
+
+func TestServer(t *testing.T) {
+	var (
+		s        = newSession(t, fakeGno)
+		send     = s.send
+		recv     = s.recv
+		mainFile = s.mainFile
+	)
+
+	send(&dap.InitializeRequest{Request: dap.Request{Command: "initialize"}})
+	assert.IsType(t, &dap.InitializeResponse{}, recv())
+
+	s.launch()
+
+	send(&dap.SetBreakpointsRequest{
+		Request: dap.Request{Command: "setBreakpoints"},
+		Arguments: dap.SetBreakpointsArguments{
+			Source:      dap.Source{Path: mainFile},
+			Breakpoints: []dap.SourceBreakpoint{{Line: 4}},
+		},
+	})
+	bps, ok := recv().(*dap.SetBreakpointsResponse)
+	require.True(t, ok)
+	require.Len(t, bps.Body.Breakpoints, 1)
+	assert.True(t, bps.Body.Breakpoints[0].Verified)
+
+	send(&dap.ConfigurationDoneRequest{Request: dap.Request{Command: "configurationDone"}})
+	assert.IsType(t, &dap.ConfigurationDoneResponse{}, recv())
+	stopped, ok := recv().(*dap.StoppedEvent)
+	require.True(t, ok)
+	assert.Equal(t, "breakpoint", stopped.Body.Reason)
+
+	send(&dap.StackTraceRequest{Request: dap.Request{Command: "stackTrace"}})
+	stack, ok := recv().(*dap.StackTraceResponse)
+	require.True(t, ok)
+	require.Len(t, stack.Body.StackFrames, 1)
+	assert.Equal(t, "main.main", stack.Body.StackFrames[0].Name)
+	assert.Equal(t, mainFile, stack.Body.StackFrames[0].Source.Path)
+	assert.Equal(t, 4, stack.Body.StackFrames[0].Line)
+
+	send(&dap.VariablesRequest{
+		Request:   dap.Request{Command: "variables"},
+		Arguments: dap.VariablesArguments{VariablesReference: 1},
+	})
+	vars, ok := recv().(*dap.VariablesResponse)
+	require.True(t, ok)
+	assert.Equal(t, []dap.Variable{{Name: "x", Value: "(1 int)"}}, vars.Body.Variables)
+
+	// the frames can't be queried while the program runs
+	send(&dap.NextRequest{Request: dap.Request{Command: "next"}})
+	assert.IsType(t, &dap.NextResponse{}, recv())
+	send(&dap.StackTraceRequest{Request: dap.Request{Command: "stackTrace"}})
+	errResp, ok := recv().(*dap.ErrorResponse)
+	require.True(t, ok)
+	assert.Equal(t, "program is running", errResp.Message)
+	send(&dap.NextRequest{Request: dap.Request{Command: "next"}})
+	errResp, ok = recv().(*dap.ErrorResponse)
+	require.True(t, ok)
+	assert.Equal(t, "program is running", errResp.Message)
+	stopped, ok = recv().(*dap.StoppedEvent)
+	require.True(t, ok)
+	assert.Equal(t, "step", stopped.Body.Reason)
+
+	send(&dap.EvaluateRequest{
+		Request:   dap.Request{Command: "evaluate"},
+		Arguments: dap.EvaluateArguments{Expression: "x"},
+	})
+	eval, ok := recv().(*dap.EvaluateResponse)
+	require.True(t, ok)
+	assert.Equal(t, "(1 int)", eval.Body.Result)
+
+	send(&dap.DisconnectRequest{Request: dap.Request{Command: "disconnect"}})
+	assert.IsType(t, &dap.DisconnectResponse{}, recv())
+	require.NoError(t, <-s.done)
+}
+
+func TestServerStopRunning(t *testing.T) {
+	// the program never stops once continued
+	s := newSession(t, `#!/bin/sh
+printf 'dbg> '
+while read line; do
+	case "$line" in
+	continue) touch running; exec sleep 60 ;;
+	*) printf 'dbg> ' ;;
+	esac
+done
+`)
+	s.send(&dap.InitializeRequest{Request: dap.Request{Command: "initialize"}})
+	assert.IsType(t, &dap.InitializeResponse{}, s.recv())
+	s.launch()
+	s.send(&dap.ConfigurationDoneRequest{Request: dap.Request{Command: "configurationDone"}})
+	assert.IsType(t, &dap.ConfigurationDoneResponse{}, s.recv())
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(filepath.Join(filepath.Dir(s.mainFile), "running"))
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// recvUntil reads the messages until isT returns true, failing on errors.
+	recvUntil := func(isT func(dap.Message) bool) {
+		for {
+			msg := s.recv()
+			if errResp, ok := msg.(*dap.ErrorResponse); ok {
+				t.Fatalf("unexpected error %s", errResp.Message)
+			}
+			if isT(msg) {
+				return
+			}
+		}
+	}
+	// stop is called twice
+	s.send(&dap.TerminateRequest{Request: dap.Request{Command: "terminate"}})
+	s.send(&dap.DisconnectRequest{Request: dap.Request{Command: "disconnect"}})
+	recvUntil(func(msg dap.Message) bool {
+		_, ok := msg.(*dap.DisconnectResponse)
+		return ok
+	})
+	select {
+	case err := <-s.done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server didn't stop")
+	}
+}
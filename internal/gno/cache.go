@@ -0,0 +1,162 @@
+package gno
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// cacheVersion is the version of the cache entries. It must be incremented
+// when Symbol, cacheEntry or the parsing of the files change, so the entries
+// of older versions are ignored.
+const cacheVersion = "v5"
+
+// A SymbolCache stores the symbols of the parsed files on disk, so they are
+// reused across sessions. The entries are keyed by the file path and are valid
+// as long as the hash of the file content doesn't change. Each index has its
+// own directory, whose entries not used when the index is loaded are removed.
+type SymbolCache struct {
+	dir string
+}
+
+// cacheEntry is the cached content of a parsed file.
+type cacheEntry struct {
+	// Hash is the hash of the file content.
+	Hash string
+	// Name and Doc are the name and the documentation of the package clause.
+	Name    string
+	Symbols []Symbol
+	Doc     string
+}
+
+// NewSymbolCache returns a cache which stores its entries in dir.
+func NewSymbolCache(dir string) *SymbolCache {
+	return &SymbolCache{dir: filepath.Join(dir, cacheVersion)}
+}
+
+// scope returns the cache of the index of rootDir whose working directory is
+// wd, in its own sub-directory so the entries of the other indexes aren't
+// pruned with its entries.
+func (c *SymbolCache) scope(wd, rootDir string) *SymbolCache {
+	if c == nil {
+		return nil
+	}
+	sum := sha256.Sum256([]byte(wd + "\x00" + rootDir))
+	return &SymbolCache{dir: filepath.Join(c.dir, hex.EncodeToString(sum[:8]))}
+}
+
+// SymbolCacheDir returns the default directory of the symbol cache, inside the
+// user cache directory, or an empty string if there's none.
+func SymbolCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "gnols", "symbols")
+}
+
+// get returns the entry of filename, whose symbols are all the symbols of the
+// file or only the exported ones, if its hash is still hash.
+func (c *SymbolCache) get(filename string, exported bool, hash string) (cacheEntry, bool) {
+	var entry cacheEntry
+	if c == nil {
+		return entry, false
+	}
+	f, err := os.Open(c.path(filename, exported))
+	if err != nil {
+		return entry, false
+	}
+	defer f.Close()
+	if err := gob.NewDecoder(f).Decode(&entry); err != nil {
+		slog.Warn("invalid symbol cache entry", "file", filename, "err", err)
+		return entry, false
+	}
+	return entry, entry.Hash == hash
+}
+
+// put stores the entry of filename. Errors are only logged, the cache being
+// an optimization.
+func (c *SymbolCache) put(filename string, exported bool, entry cacheEntry) {
+	if c == nil {
+		return
+	}
+	path := c.path(filename, exported)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		slog.Warn("create symbol cache", "err", err)
+		return
+	}
+	// write to a temporary file so concurrent servers never read a partial
+	// entry
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		slog.Warn("write symbol cache", "file", filename, "err", err)
+		return
+	}
+	err = gob.NewEncoder(f).Encode(entry)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		slog.Warn("write symbol cache", "file", filename, "err", err)
+	}
+}
+
+// delete removes the entries of filename.
+func (c *SymbolCache) delete(filename string) {
+	if c == nil {
+		return
+	}
+	for _, exported := range []bool{false, true} {
+		err := os.Remove(c.path(filename, exported))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			slog.Warn("delete symbol cache", "file", filename, "err", err)
+		}
+	}
+}
+
+// prune removes the entries whose path isn't in keep, including the temporary
+// files left by interrupted writes.
+func (c *SymbolCache) prune(keep map[string]bool) {
+	if c == nil {
+		return
+	}
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || keep[path] {
+			return err
+		}
+		if err := os.Remove(path); err != nil {
+			slog.Warn("prune symbol cache", "path", path, "err", err)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		slog.Warn("prune symbol cache", "err", err)
+	}
+}
+
+// path returns the path of the entry of filename.
+func (c *SymbolCache) path(filename string, exported bool) string {
+	key := filename
+	if exported {
+		key += ":exported"
+	}
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+	// spread the entries across sub-directories
+	return filepath.Join(c.dir, name[:2], name[2:]+".gob")
+}
+
+// hashContent returns the hash of a file content.
+func hashContent(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
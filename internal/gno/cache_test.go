@@ -0,0 +1,114 @@
+package gno_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jdkato/gnols/internal/gno"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSymbolCache(t *testing.T) {
+	var (
+		root     = t.TempDir()
+		cacheDir = t.TempDir()
+		cache    = gno.NewSymbolCache(cacheDir)
+		file     = filepath.Join(root, "a.gno")
+		old      = time.Now().Add(-time.Hour).Truncate(time.Second)
+	)
+	// entries returns the cache entries and their modification time, after
+	// resetting it to old.
+	entries := func() map[string]time.Time {
+		t.Helper()
+		res := make(map[string]time.Time)
+		err := filepath.WalkDir(cacheDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			fi, err := d.Info()
+			if err != nil {
+				return err
+			}
+			res[path] = fi.ModTime()
+			return os.Chtimes(path, old, old)
+		})
+		require.NoError(t, err)
+		return res
+	}
+	load := func() []gno.Package {
+		t.Helper()
+		x := gno.NewIndex("", root, cache)
+		require.NoError(t, x.Load())
+		return x.Packages()
+	}
+	require.NoError(t, os.WriteFile(file, []byte("package a\n\nfunc A() {}\n"), 0o644))
+
+	pkgs := load()
+	require.Len(t, pkgs, 1)
+	assert.Equal(t, "A", pkgs[0].Symbols[0].Name)
+	require.Len(t, entries(), 1)
+
+	// unchanged file, the entry is reused
+	assert.Equal(t, pkgs, load())
+	for path, mtime := range entries() {
+		assert.True(t, mtime.Equal(old), "entry %s rewritten", path)
+	}
+
+	// changed file, the entry is invalidated
+	require.NoError(t, os.WriteFile(file, []byte("package a\n\nfunc B() {}\n"), 0o644))
+	pkgs = load()
+	require.Len(t, pkgs, 1)
+	assert.Equal(t, "B", pkgs[0].Symbols[0].Name)
+	for path, mtime := range entries() {
+		assert.False(t, mtime.Equal(old), "entry %s not rewritten", path)
+	}
+
+	// deleted file, the entry is removed
+	x := gno.NewIndex("", root, cache)
+	require.NoError(t, x.Load())
+	require.NoError(t, os.Remove(file))
+	require.NoError(t, x.Update(file))
+	assert.Empty(t, entries())
+}
+
+func TestSymbolCachePrune(t *testing.T) {
+	var (
+		root     = t.TempDir()
+		other    = t.TempDir()
+		cacheDir = t.TempDir()
+		cache    = gno.NewSymbolCache(cacheDir)
+	)
+	count := func() int {
+		t.Helper()
+		n := 0
+		err := filepath.WalkDir(cacheDir, func(_ string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				n++
+			}
+			return err
+		})
+		require.NoError(t, err)
+		return n
+	}
+	load := func(dir string) {
+		t.Helper()
+		require.NoError(t, gno.NewIndex("", dir, cache).Load())
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.gno"), []byte("package a\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "b.gno"), []byte("package a\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(other, "c.gno"), []byte("package c\n"), 0o644))
+	load(root)
+	load(other)
+	require.Equal(t, 3, count())
+
+	// b.gno is deleted while no index watches it
+	require.NoError(t, os.Remove(filepath.Join(root, "b.gno")))
+	load(root)
+
+	// the entries of the other index are kept
+	assert.Equal(t, 2, count())
+}
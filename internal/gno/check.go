@@ -0,0 +1,294 @@
+package gno
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// Resolver returns the directory of the package imported with path.
+type Resolver func(path string) (dir string, ok bool)
+
+// Checker type-checks Gno packages from their sources, using the Go type
+// checker. Imports are resolved with a Resolver and checked only once.
+//
+// Errors are ignored, so the type information is as complete as possible even
+// if the sources contain errors or if some imports can't be resolved.
+type Checker struct {
+	Fset *token.FileSet
+
+	resolve Resolver
+	// overlay contains the content of the files that differ from the disk,
+	// indexed by filename.
+	overlay map[string]string
+	// sources contains the content of the parsed files, indexed by filename.
+	sources map[string]string
+	// files contains all the parsed files, indexed by filename.
+	files map[string]*ast.File
+	// pkgs contains the imported packages, indexed by import path.
+	pkgs map[string]*types.Package
+	// dirs contains the directories of the imported packages, indexed by
+	// import path.
+	dirs    map[string]string
+	loading map[string]bool
+	// updates is the number of updates since the checker was reset.
+	updates int
+}
+
+// maxUpdates is the number of updates after which a checker starts over: each
+// update parses the overlay files again into Fset, which can't shrink.
+const maxUpdates = 100
+
+// CheckedPackage is a type-checked package.
+type CheckedPackage struct {
+	Types *types.Package
+	Info  *types.Info
+	Files []*ast.File
+}
+
+func NewChecker(resolve Resolver, overlay map[string]string) *Checker {
+	c := &Checker{resolve: resolve}
+	c.reset(overlay)
+	return c
+}
+
+// reset drops the parsed files and the imported packages.
+func (c *Checker) reset(overlay map[string]string) {
+	c.Fset = token.NewFileSet()
+	c.overlay = overlay
+	c.sources = make(map[string]string)
+	c.files = make(map[string]*ast.File)
+	c.pkgs = make(map[string]*types.Package)
+	c.dirs = make(map[string]string)
+	c.loading = make(map[string]bool)
+	c.updates = 0
+}
+
+// Update replaces the content of the files that differ from the disk, so the
+// checker can be reused once they change. The packages of the directories of
+// the old and new overlays are parsed and checked again, while the other
+// imported packages are kept, until the checker is reset after maxUpdates
+// updates.
+func (c *Checker) Update(overlay map[string]string) {
+	c.updates++
+	if c.updates >= maxUpdates {
+		c.reset(overlay)
+		return
+	}
+	dirs := make(map[string]bool)
+	for filename := range c.overlay {
+		dirs[filepath.Dir(filename)] = true
+	}
+	for filename := range overlay {
+		dirs[filepath.Dir(filename)] = true
+	}
+	for filename := range c.files {
+		if dirs[filepath.Dir(filename)] {
+			delete(c.files, filename)
+			delete(c.sources, filename)
+		}
+	}
+	for path, dir := range c.dirs {
+		if dirs[dir] {
+			delete(c.pkgs, path)
+			delete(c.dirs, path)
+		}
+	}
+	c.overlay = overlay
+}
+
+// Check type-checks the package of filename, using path as its import path.
+// Test files are included only if filename is a test file.
+func (c *Checker) Check(path, filename string) (*CheckedPackage, error) {
+	file, err := c.parseFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var files []*ast.File
+	if strings.HasSuffix(filename, "_filetest.gno") {
+		// filetests are standalone
+		files = []*ast.File{file}
+	} else {
+		files, err = c.parseDir(filepath.Dir(filename), file.Name.Name, strings.HasSuffix(filename, "_test.gno"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Implicits:  make(map[ast.Node]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		Scopes:     make(map[ast.Node]*types.Scope),
+	}
+	conf := types.Config{
+		Importer: c,
+		Error:    func(error) {},
+	}
+	pkg, _ := conf.Check(path, c.Fset, files, info)
+	return &CheckedPackage{Types: pkg, Info: info, Files: files}, nil
+}
+
+// File returns the parsed file filename, or nil if it hasn't been parsed.
+func (c *Checker) File(filename string) *ast.File {
+	return c.files[filename]
+}
+
+// Loaded returns true if the package imported with path has been checked
+// from its sources.
+func (c *Checker) Loaded(path string) bool {
+	return c.pkgs[path] != nil
+}
+
+// Import implements types.Importer.
+func (c *Checker) Import(path string) (*types.Package, error) {
+	if pkg, ok := c.pkgs[path]; ok {
+		if pkg == nil {
+			return nil, fmt.Errorf("package %s not found", path)
+		}
+		return pkg, nil
+	}
+	if c.loading[path] {
+		return nil, fmt.Errorf("import cycle with %s", path)
+	}
+	c.loading[path] = true
+	defer delete(c.loading, path)
+
+	dir, ok := c.resolve(path)
+	if !ok {
+		c.pkgs[path] = nil
+		return nil, fmt.Errorf("package %s not found", path)
+	}
+	files, err := c.parseDir(dir, "", false)
+	if err != nil || len(files) == 0 {
+		c.pkgs[path] = nil
+		return nil, fmt.Errorf("package %s not found in %s", path, dir)
+	}
+	conf := types.Config{
+		Importer:         c,
+		Error:            func(error) {},
+		IgnoreFuncBodies: true,
+	}
+	pkg, _ := conf.Check(path, c.Fset, files, nil)
+	c.pkgs[path], c.dirs[path] = pkg, dir
+	return pkg, nil
+}
+
+// DeclSymbol returns the symbol of the declaration of obj, or nil if obj
+// isn't declared in the checked sources.
+func (c *Checker) DeclSymbol(obj types.Object) *Symbol {
+	path := c.DeclPath(obj)
+	if path == nil {
+		return nil
+	}
+	sym := DeclSymbol(c.Fset, c.sources[c.Fset.File(obj.Pos()).Name()], path)
+	if sym != nil {
+		// the declaration may have multiple names
+		sym.Name = obj.Name()
+	}
+	return sym
+}
+
+// Text returns the source text of n, a node of the checked sources.
+func (c *Checker) Text(n ast.Node) string {
+	tf := c.Fset.File(n.Pos())
+	if tf == nil {
+		return ""
+	}
+	return source{file: tf, text: c.sources[tf.Name()]}.of(n)
+}
+
+// DeclPath returns the path of nodes enclosing the declaration of obj, as
+// returned by astutil.PathEnclosingInterval.
+func (c *Checker) DeclPath(obj types.Object) []ast.Node {
+	if obj == nil || !obj.Pos().IsValid() {
+		return nil
+	}
+	tf := c.Fset.File(obj.Pos())
+	if tf == nil {
+		return nil
+	}
+	file := c.files[tf.Name()]
+	if file == nil {
+		return nil
+	}
+	path, _ := astutil.PathEnclosingInterval(file, obj.Pos(), obj.Pos())
+	return path
+}
+
+// parseDir parses the Gno files of dir whose package name is pkgName, or the
+// first package name found if pkgName is empty.
+func (c *Checker) parseDir(dir, pkgName string, withTests bool) ([]*ast.File, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		filenames []string
+		keep      = func(name string) bool {
+			if filepath.Ext(name) != ".gno" || strings.HasSuffix(name, "_filetest.gno") {
+				return false
+			}
+			return withTests || !strings.HasSuffix(name, "_test.gno")
+		}
+	)
+	for _, e := range entries {
+		if !e.IsDir() && keep(e.Name()) {
+			filenames = append(filenames, filepath.Join(dir, e.Name()))
+		}
+	}
+	// include the files which aren't saved yet
+	for filename := range c.overlay {
+		if filepath.Dir(filename) == dir && keep(filepath.Base(filename)) && !slices.Contains(filenames, filename) {
+			filenames = append(filenames, filename)
+		}
+	}
+	sort.Strings(filenames)
+
+	var files []*ast.File
+	for _, filename := range filenames {
+		file, err := c.parseFile(filename)
+		if err != nil {
+			continue
+		}
+		if pkgName == "" {
+			pkgName = file.Name.Name
+		}
+		if file.Name.Name == pkgName {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+func (c *Checker) parseFile(filename string) (*ast.File, error) {
+	if f, ok := c.files[filename]; ok {
+		return f, nil
+	}
+	content, ok := c.overlay[filename]
+	if !ok {
+		bz, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		content = string(bz)
+	}
+	file, _ := parser.ParseFile(c.Fset, filename, content, parser.ParseComments)
+	if file == nil || file.Name == nil {
+		return nil, errors.New("invalid gno file " + filename)
+	}
+	c.files[filename] = file
+	c.sources[filename] = content
+	return file, nil
+}
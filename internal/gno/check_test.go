@@ -0,0 +1,129 @@
+package gno_test
+
+import (
+	"go/types"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jdkato/gnols/internal/gno"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecker(t *testing.T) {
+	var (
+		dir    = t.TempDir()
+		fooDir = filepath.Join(dir, "foo")
+		subDir = filepath.Join(dir, "sub")
+	)
+	require.NoError(t, os.MkdirAll(fooDir, os.ModePerm))
+	require.NoError(t, os.MkdirAll(subDir, os.ModePerm))
+	require.NoError(t, os.WriteFile(filepath.Join(subDir, "sub.gno"), []byte(`package sub
+
+// T is a type.
+type T struct{ A int }
+`), os.ModePerm))
+	require.NoError(t, os.WriteFile(filepath.Join(fooDir, "bar.gno"), []byte(`package foo
+
+var Bar = 1
+`), os.ModePerm))
+	fooFile := filepath.Join(fooDir, "foo.gno")
+	resolve := func(path string) (string, bool) {
+		if path == "sub" {
+			return subDir, true
+		}
+		return "", false
+	}
+	// foo.gno only exists in the overlay
+	chk := gno.NewChecker(resolve, map[string]string{fooFile: `package foo
+
+import (
+	"sub"
+	"unknown"
+)
+
+var X = sub.T{}
+
+var Y = unknown.Z
+`})
+
+	pkg, err := chk.Check("foo", fooFile)
+
+	require.NoError(t, err)
+	assert.Len(t, pkg.Files, 2)
+	assert.True(t, chk.Loaded("sub"))
+	assert.False(t, chk.Loaded("unknown"))
+	x := pkg.Types.Scope().Lookup("X")
+	require.NotNil(t, x)
+	assert.Equal(t, "sub.T", x.Type().String())
+	assert.NotNil(t, pkg.Types.Scope().Lookup("Bar"))
+	sym := chk.DeclSymbol(x.Type().(*types.Named).Obj())
+	require.NotNil(t, sym)
+	assert.Equal(t, gno.Symbol{
+		Name:      "T",
+		Doc:       "T is a type.",
+		Signature: "T struct{ A int }",
+		Kind:      "struct",
+		Fields: []gno.Symbol{
+			{
+				Name: "A", Signature: "A int", Kind: "field", Type: "int",
+				Pos: &gno.Position{File: "sub.gno", Line: 4, Column: 16},
+			},
+		},
+		Pos: &gno.Position{File: "sub.gno", Line: 4, Column: 6},
+	}, *sym)
+}
+
+func TestCheckerUpdate(t *testing.T) {
+	var (
+		dir    = t.TempDir()
+		fooDir = filepath.Join(dir, "foo")
+		subDir = filepath.Join(dir, "sub")
+	)
+	require.NoError(t, os.MkdirAll(fooDir, os.ModePerm))
+	require.NoError(t, os.MkdirAll(subDir, os.ModePerm))
+	require.NoError(t, os.WriteFile(filepath.Join(subDir, "sub.gno"), []byte("package sub\n\ntype T struct{}\n"), os.ModePerm))
+	fooFile := filepath.Join(fooDir, "foo.gno")
+	resolved := 0
+	resolve := func(path string) (string, bool) {
+		resolved++
+		return subDir, path == "sub"
+	}
+	chk := gno.NewChecker(resolve, map[string]string{fooFile: "package foo\n\nimport \"sub\"\n\nvar X = sub.T{}\n"})
+	_, err := chk.Check("foo", fooFile)
+	require.NoError(t, err)
+
+	// the file changed, the imports are kept
+	chk.Update(map[string]string{fooFile: "package foo\n\nimport \"sub\"\n\nvar Y = sub.T{}\n"})
+	pkg, err := chk.Check("foo", fooFile)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, resolved)
+	assert.Nil(t, pkg.Types.Scope().Lookup("X"))
+	y := pkg.Types.Scope().Lookup("Y")
+	require.NotNil(t, y)
+	assert.Equal(t, "sub.T", y.Type().String())
+	assert.NotNil(t, chk.DeclSymbol(y.Type().(*types.Named).Obj()))
+}
+
+func TestCheckerUpdateBounded(t *testing.T) {
+	var (
+		dir     = t.TempDir()
+		fooFile = filepath.Join(dir, "foo.gno")
+		content = "package foo\n\nvar X = 1\n"
+	)
+	chk := gno.NewChecker(func(string) (string, bool) { return "", false }, map[string]string{fooFile: content})
+	_, err := chk.Check("foo", fooFile)
+	require.NoError(t, err)
+
+	// each update parses the file again, the file set must not grow forever
+	const updates = 1000
+	for i := 0; i < updates; i++ {
+		chk.Update(map[string]string{fooFile: content})
+		pkg, err := chk.Check("foo", fooFile)
+		require.NoError(t, err)
+		require.NotNil(t, pkg.Types.Scope().Lookup("X"))
+	}
+	assert.Less(t, chk.Fset.Base(), updates/2*(len(content)+1))
+}
@@ -0,0 +1,88 @@
+package gno
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"go.lsp.dev/uri"
+	"golang.org/x/tools/cover"
+)
+
+// Coverage runs the tests of a Gno package with coverage enabled and returns
+// the spans of the statements that were never executed:
+//
+// gno test -root-dir <root> -cover -coverprofile <tmp> <pkg_path>
+func (m *BinManager) Coverage(pkg string) ([]Span, error) {
+	profile, err := os.CreateTemp("", "gnols-coverage-*.out")
+	if err != nil {
+		return nil, err
+	}
+	profile.Close()
+	defer os.Remove(profile.Name())
+
+	args := []string{
+		"test",
+		"-root-dir",
+		m.root,
+		"-cover",
+		"-coverprofile",
+		profile.Name(),
+		pkg,
+	}
+	cmd := exec.Command(m.gno, args...) //nolint:gosec
+	cmd.Dir = pkg
+	bz, errTest := cmd.CombinedOutput()
+
+	f, err := os.Open(profile.Name())
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	spans, err := ParseCoverProfile(pkg, f)
+	if err != nil {
+		return nil, err
+	}
+	if len(spans) == 0 && errTest != nil {
+		// failing tests still produce a profile, only report the error if there's
+		// nothing to show.
+		return nil, fmt.Errorf("running '%s %s': %w: %s", m.gno, strings.Join(args, " "), errTest, string(bz))
+	}
+	return spans, nil
+}
+
+// ParseCoverProfile parses a coverage profile and returns the spans of the
+// blocks that have a zero count.
+//
+// The profile file names are import paths, so they are resolved against pkg
+// using their base name.
+func ParseCoverProfile(pkg string, r io.Reader) ([]Span, error) {
+	profiles, err := cover.ParseProfilesFromReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("parse cover profile: %w", err)
+	}
+	var spans []Span
+	for _, p := range profiles {
+		file := uri.File(filepath.Join(pkg, filepath.Base(p.FileName)))
+		for _, b := range p.Blocks {
+			if b.Count > 0 {
+				continue
+			}
+			spans = append(spans, Span{
+				URI: file,
+				Start: Location{
+					Line:   uint32(b.StartLine),
+					Column: uint32(b.StartCol),
+				},
+				End: Location{
+					Line:   uint32(b.EndLine),
+					Column: uint32(b.EndCol),
+				},
+			})
+		}
+	}
+	return spans, nil
+}
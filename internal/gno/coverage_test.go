@@ -0,0 +1,58 @@
+package gno_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jdkato/gnols/internal/gno"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.lsp.dev/uri"
+)
+
+func TestParseCoverProfile(t *testing.T) {
+	tests := []struct {
+		name          string
+		profile       string
+		expectedSpans []gno.Span
+	}{
+		{
+			name:    "empty",
+			profile: "",
+		},
+		{
+			name: "all covered",
+			profile: `mode: set
+gno.land/r/demo/foo/foo.gno:3.14,5.2 1 1
+`,
+		},
+		{
+			name: "uncovered blocks",
+			profile: `mode: set
+gno.land/r/demo/foo/foo.gno:3.14,5.2 1 1
+gno.land/r/demo/foo/foo.gno:7.20,9.3 2 0
+gno.land/r/demo/foo/bar.gno:1.1,2.10 1 0
+`,
+			expectedSpans: []gno.Span{
+				{
+					URI:   uri.File("/pkg/bar.gno"),
+					Start: gno.Location{Line: 1, Column: 1},
+					End:   gno.Location{Line: 2, Column: 10},
+				},
+				{
+					URI:   uri.File("/pkg/foo.gno"),
+					Start: gno.Location{Line: 7, Column: 20},
+					End:   gno.Location{Line: 9, Column: 3},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spans, err := gno.ParseCoverProfile("/pkg", strings.NewReader(tt.profile))
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedSpans, spans)
+		})
+	}
+}
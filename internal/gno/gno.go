@@ -6,13 +6,16 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/sourcegraph/go-diff/diff"
 	"go.lsp.dev/protocol"
@@ -91,6 +94,44 @@ func (m *BinManager) GnoBin() string {
 	return m.gno
 }
 
+// Root returns the path to the gno repository, or an empty string if it isn't
+// configured.
+func (m *BinManager) Root() string {
+	return m.root
+}
+
+// Version returns the version of the `gno` binary, as printed by
+// `gno version`.
+func (m *BinManager) Version() (string, error) {
+	return BinaryVersion(m.gno)
+}
+
+// BinaryVersion returns the version of the gno binary bin.
+func BinaryVersion(bin string) (string, error) {
+	bz, err := exec.Command(bin, "version").CombinedOutput() //nolint:gosec
+	if err != nil {
+		return "", fmt.Errorf("running '%s version': %w: %s", bin, err, string(bz))
+	}
+	version, ok := ParseVersion(string(bz))
+	if !ok {
+		return "", fmt.Errorf("unexpected output of '%s version': %s", bin, string(bz))
+	}
+	return version, nil
+}
+
+var reVersion = regexp.MustCompile(`(?m)^gno version:\s*(\S+)`)
+
+// ParseVersion parses the output of `gno version`:
+//
+// gno version: develop.4b6fe26c
+func ParseVersion(output string) (string, bool) {
+	m := reVersion.FindStringSubmatch(output)
+	if m == nil {
+		return "", false
+	}
+	return m[1], true
+}
+
 func (m *BinManager) RunGopls(ctx context.Context, args ...string) ([]byte, error) {
 	// Prepare call to gopls
 	cmd := exec.CommandContext(ctx, m.gopls, args...) //nolint:gosec
@@ -158,6 +199,42 @@ func (m *BinManager) RunTest(pkg, name string) ([]byte, error) {
 	return cmd.CombinedOutput()
 }
 
+// TestResult is the result of a test run by `gno test -verbose`.
+type TestResult struct {
+	Name     string
+	Passed   bool
+	Duration time.Duration
+}
+
+var reTestResult = regexp.MustCompile(`(?m)^\s*--- (PASS|FAIL): (\S+) \(([\d.]+s)\)`)
+
+// ParseTestResults parses the output of `gno test -verbose`:
+//
+// --- PASS: TestName (0.01s)
+func ParseTestResults(output string) []TestResult {
+	var results []TestResult
+	for _, match := range reTestResult.FindAllStringSubmatch(output, -1) {
+		d, _ := time.ParseDuration(match[3])
+		results = append(results, TestResult{
+			Name:     match[2],
+			Passed:   match[1] == "PASS",
+			Duration: d,
+		})
+	}
+	return results
+}
+
+// Run executes a Gno file, streaming its output to stdout and stderr:
+//
+// gno run -root-dir <root> <file>
+func (m *BinManager) Run(ctx context.Context, file string, stdout, stderr io.Writer) error {
+	cmd := exec.CommandContext(ctx, m.gno, "run", "-root-dir", m.root, file) //nolint:gosec
+	cmd.Dir = filepath.Dir(file)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return cmd.Run()
+}
+
 // Lint transpiles and builds a Gno package and returns any errors.
 //
 // In practice, this means:
@@ -2,6 +2,7 @@ package gno_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/jdkato/gnols/internal/gno"
 	"github.com/stretchr/testify/assert"
@@ -78,3 +79,32 @@ dir/file2.gno.gen.go:4:5-6
 		})
 	}
 }
+
+func TestParseTestResults(t *testing.T) {
+	output := `=== RUN   TestA
+--- PASS: TestA (0.01s)
+=== RUN   TestB
+    b_test.gno:10: oops
+--- FAIL: TestB (1.50s)
+FAIL
+`
+	results := gno.ParseTestResults(output)
+
+	assert.Equal(t, []gno.TestResult{
+		{Name: "TestA", Passed: true, Duration: 10 * time.Millisecond},
+		{Name: "TestB", Passed: false, Duration: 1500 * time.Millisecond},
+	}, results)
+}
+
+func TestParseVersion(t *testing.T) {
+	version, ok := gno.ParseVersion("gno version: develop.4b6fe26c\n")
+	assert.True(t, ok)
+	assert.Equal(t, "develop.4b6fe26c", version)
+
+	version, ok = gno.ParseVersion("gno version: chain/test4.2\n")
+	assert.True(t, ok)
+	assert.Equal(t, "chain/test4.2", version)
+
+	_, ok = gno.ParseVersion("flag provided but not defined: version\n")
+	assert.False(t, ok)
+}
@@ -0,0 +1,310 @@
+package gno
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// SymbolID returns the ID of sym, a symbol of pkg. Like in go doc, the ID is
+// the import path of pkg followed by the names of sym's parent type, if any,
+// and sym, separated by dots, e.g. "gno.land/p/demo/avl.Tree.Get".
+func (p *Package) SymbolID(sym Symbol) string {
+	for _, s := range p.Symbols {
+		if s.Name == sym.Name && s.Signature == sym.Signature {
+			return p.ImportPath + "." + sym.Name
+		}
+		for _, f := range s.Fields {
+			if f.Name == sym.Name && f.Signature == sym.Signature {
+				return p.ImportPath + "." + s.Name + "." + sym.Name
+			}
+		}
+	}
+	return p.ImportPath + "." + sym.Name
+}
+
+// LookupSymbol returns the package and the symbol of pkgs identified by id,
+// as returned by SymbolID. The symbol is nil if id is the import path of the
+// package.
+//
+// Import paths may contain dots, e.g. a folder without gno.mod named
+// "my.app", so id is matched against the import paths of pkgs rather than
+// split at its first dot.
+func LookupSymbol(pkgs []Package, id string) (*Package, *Symbol) {
+	var (
+		pkg   *Package
+		names []string
+	)
+	for i := range pkgs {
+		path := pkgs[i].ImportPath
+		if pkg != nil && len(path) <= len(pkg.ImportPath) {
+			// keep the longest matching import path
+			continue
+		}
+		if id == path {
+			pkg, names = &pkgs[i], nil
+		} else if rest, ok := strings.CutPrefix(id, path+"."); ok {
+			pkg, names = &pkgs[i], strings.Split(rest, ".")
+		}
+	}
+	if pkg == nil || len(names) == 0 {
+		return pkg, nil
+	}
+	syms := pkg.Symbols
+	for j, name := range names {
+		var found *Symbol
+		for k := range syms {
+			if syms[k].Name == name {
+				found = &syms[k]
+				break
+			}
+		}
+		if found == nil {
+			return nil, nil
+		}
+		if j == len(names)-1 {
+			return pkg, found
+		}
+		syms = found.Fields
+	}
+	return nil, nil
+}
+
+// An Index indexes the packages of a directory tree. It keeps the symbols of
+// each file, so when files change only them are parsed again.
+type Index struct {
+	wd, rootDir string
+	// files contains the parsed files, indexed by directory and path.
+	files map[string]map[string]indexedFile
+	// pkgs contains the packages with symbols, indexed by directory.
+	pkgs map[string]*Package
+	// cache contains the symbols of the files parsed in previous sessions, it
+	// can be nil.
+	cache *SymbolCache
+	// loaded contains the paths of the cache entries used during Load, the
+	// other entries of the index are pruned once it's loaded.
+	loaded map[string]bool
+}
+
+// indexedFile contains the symbols, the package name and the package
+// documentation of a file.
+type indexedFile struct {
+	name    string
+	symbols []Symbol
+	doc     string
+}
+
+// NewIndex returns an empty index of rootDir. Like in ParsePackages, only the
+// exported symbols are indexed, except for the files of wd. If cache isn't nil,
+// the files unchanged since they were cached aren't parsed again.
+func NewIndex(wd, rootDir string, cache *SymbolCache) *Index {
+	return &Index{
+		wd:      wd,
+		rootDir: rootDir,
+		files:   make(map[string]map[string]indexedFile),
+		pkgs:    make(map[string]*Package),
+		cache:   cache.scope(wd, rootDir),
+	}
+}
+
+// Load parses all the gno files of the tree. The cache entries of the files
+// which aren't in the tree anymore are removed.
+func (x *Index) Load() error {
+	if x.cache != nil {
+		x.loaded = make(map[string]bool)
+		defer func() { x.loaded = nil }()
+	}
+	if err := x.loadDir(x.rootDir); err != nil {
+		return err
+	}
+	x.cache.prune(x.loaded)
+	return nil
+}
+
+// loadDir parses the gno files of dir and its sub-directories.
+func (x *Index) loadDir(dir string) error {
+	dirs, err := getDirs(dir)
+	if err != nil {
+		return err
+	}
+	for _, dir := range dirs {
+		files, err := getFiles(dir)
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			if err := x.parseFile(file); err != nil {
+				return err
+			}
+		}
+		x.updatePkg(dir)
+	}
+	return nil
+}
+
+// Update refreshes the index after path has been created, changed or deleted.
+// path can be a gno file, a gno.mod file or a directory. Other paths are
+// ignored.
+func (x *Index) Update(path string) error {
+	if !x.indexed(path) {
+		return nil
+	}
+	fi, err := os.Stat(path)
+	switch {
+	case filepath.Base(path) == ModFileName:
+		// the import paths of the module's packages may have changed
+		for dir, pkg := range x.pkgs {
+			if inDir(filepath.Dir(path), dir) {
+				x.setModule(pkg)
+			}
+		}
+	case errors.Is(err, fs.ErrNotExist):
+		x.remove(path)
+	case err != nil:
+		return err
+	case fi.IsDir():
+		return x.loadDir(path)
+	case isGnoFile(path):
+		if err := x.parseFile(path); err != nil {
+			return err
+		}
+		x.updatePkg(filepath.Dir(path))
+	}
+	return nil
+}
+
+// Packages returns the indexed packages, in the order of the directory tree.
+func (x *Index) Packages() []Package {
+	pkgs := make([]Package, 0, len(x.pkgs))
+	for _, pkg := range x.pkgs {
+		pkgs = append(pkgs, *pkg)
+	}
+	sep := string(filepath.Separator)
+	sort.Slice(pkgs, func(i, j int) bool {
+		return slices.Compare(strings.Split(pkgs[i].Dir, sep), strings.Split(pkgs[j].Dir, sep)) < 0
+	})
+	return pkgs
+}
+
+// indexed returns true if path is inside the tree, and not inside a hidden
+// directory.
+func (x *Index) indexed(path string) bool {
+	rel, err := filepath.Rel(x.rootDir, path)
+	if err != nil || !inDir(x.rootDir, path) {
+		return false
+	}
+	for _, elem := range strings.Split(filepath.Dir(rel), string(filepath.Separator)) {
+		if strings.HasPrefix(elem, ".") && elem != "." {
+			return false
+		}
+	}
+	return true
+}
+
+// parseFile parses filename and keeps its symbols, unless they are in the
+// cache.
+func (x *Index) parseFile(filename string) error {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	var (
+		dir      = filepath.Dir(filename)
+		exported = x.wd != dir
+		hash     = hashContent(content)
+	)
+	entry, ok := x.cache.get(filename, exported, hash)
+	if !ok {
+		entry, err = parseSymbols(x.wd, filename, content)
+		if err != nil {
+			return err
+		}
+		entry.Hash = hash
+		x.cache.put(filename, exported, entry)
+	}
+	if x.loaded != nil {
+		x.loaded[x.cache.path(filename, exported)] = true
+	}
+	if x.files[dir] == nil {
+		x.files[dir] = make(map[string]indexedFile)
+	}
+	x.files[dir][filename] = indexedFile{name: entry.Name, symbols: entry.Symbols, doc: entry.Doc}
+	return nil
+}
+
+// remove removes path, a file or a directory, from the index.
+func (x *Index) remove(path string) {
+	if files, ok := x.files[filepath.Dir(path)]; ok {
+		if _, ok := files[path]; ok {
+			delete(files, path)
+			x.cache.delete(path)
+			x.updatePkg(filepath.Dir(path))
+			return
+		}
+	}
+	for dir := range x.files {
+		if inDir(path, dir) {
+			for filename := range x.files[dir] {
+				x.cache.delete(filename)
+			}
+			delete(x.files, dir)
+			delete(x.pkgs, dir)
+		}
+	}
+}
+
+// updatePkg rebuilds the package of dir from the symbols of its files.
+func (x *Index) updatePkg(dir string) {
+	files := x.files[dir]
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pkg := &Package{Dir: dir}
+	for _, name := range names {
+		pkg.Symbols = append(pkg.Symbols, files[name].symbols...)
+		if pkg.Name == "" {
+			pkg.Name = files[name].name
+		}
+		if pkg.Doc == "" {
+			pkg.Doc = files[name].doc
+		}
+	}
+	if pkg.Name == "" {
+		// no valid package clause
+		pkg.Name = filepath.Base(dir)
+	}
+	if len(pkg.Symbols) == 0 {
+		delete(x.pkgs, dir)
+		return
+	}
+	x.setModule(pkg)
+	x.pkgs[dir] = pkg
+}
+
+// setModule sets the import path of pkg and its draft flag, according to its
+// gno.mod file.
+func (x *Index) setModule(pkg *Package) {
+	pkg.ImportPath = ImportPath(x.rootDir, pkg.Dir)
+	pkg.Draft = false
+	if mod, _, ok := FindModFile(pkg.Dir); ok {
+		pkg.Draft = IsDraft(mod)
+	}
+}
+
+// isGnoFile returns true if filename is a gno file which is indexed, i.e. not
+// a test file.
+func isGnoFile(filename string) bool {
+	return filepath.Ext(filename) == ".gno" && !strings.Contains(filepath.Base(filename), "_test")
+}
+
+// inDir returns true if path is dir or is inside dir.
+func inDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
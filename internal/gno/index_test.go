@@ -0,0 +1,132 @@
+package gno_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jdkato/gnols/internal/gno"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupSymbol(t *testing.T) {
+	pkgs := []gno.Package{
+		{Name: "std", ImportPath: "std"},
+		{
+			Name:       "avl",
+			ImportPath: "gno.land/p/demo/avl",
+			Symbols: []gno.Symbol{
+				{Name: "NewTree", Signature: "func NewTree() *Tree", Kind: "func"},
+				{
+					Name: "Tree", Signature: "Tree struct", Kind: "struct",
+					Fields: []gno.Symbol{{Name: "Get", Signature: "func (t *Tree) Get(key string) any", Kind: "method"}},
+				},
+			},
+		},
+		{
+			Name:       "main",
+			ImportPath: "my.app",
+			Symbols:    []gno.Symbol{{Name: "Run", Signature: "func Run()", Kind: "func"}},
+		},
+		{
+			Name:       "sub",
+			ImportPath: "my.app/sub.pkg",
+			Symbols:    []gno.Symbol{{Name: "Run", Signature: "func Run()", Kind: "func"}},
+		},
+	}
+	tests := []struct {
+		id       string
+		wantPkg  string
+		wantName string
+	}{
+		{id: "std", wantPkg: "std"},
+		{id: "gno.land/p/demo/avl", wantPkg: "gno.land/p/demo/avl"},
+		{id: "gno.land/p/demo/avl.NewTree", wantPkg: "gno.land/p/demo/avl", wantName: "NewTree"},
+		{id: "gno.land/p/demo/avl.Tree.Get", wantPkg: "gno.land/p/demo/avl", wantName: "Get"},
+		{id: "gno.land/p/demo/avl.Tree.Set"},
+		{id: "gno.land/p/demo/xxx.Tree"},
+		{id: "my.app", wantPkg: "my.app"},
+		{id: "my.app.Run", wantPkg: "my.app", wantName: "Run"},
+		{id: "my.app/sub.pkg.Run", wantPkg: "my.app/sub.pkg", wantName: "Run"},
+		{id: "my.app/sub.pkg", wantPkg: "my.app/sub.pkg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			pkg, sym := gno.LookupSymbol(pkgs, tt.id)
+			var gotPkg, gotName string
+			if pkg != nil {
+				gotPkg = pkg.ImportPath
+			}
+			if sym != nil {
+				gotName = sym.Name
+				if id := pkg.SymbolID(*sym); id != tt.id {
+					t.Errorf("SymbolID: want %q, got %q", tt.id, id)
+				}
+			}
+			if gotPkg != tt.wantPkg || gotName != tt.wantName {
+				t.Errorf("want %q %q, got %q %q", tt.wantPkg, tt.wantName, gotPkg, gotName)
+			}
+		})
+	}
+}
+
+func TestIndexUpdate(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	// pkgs returns the import paths and the symbols of the indexed packages.
+	pkgs := func(x *gno.Index) []string {
+		var res []string
+		for _, pkg := range x.Packages() {
+			s := pkg.ImportPath + ":"
+			for _, sym := range pkg.Symbols {
+				s += " " + sym.Name
+			}
+			res = append(res, s)
+		}
+		return res
+	}
+	write("a.gno", "package foo\n\nfunc A() {}\n")
+	subFile := write("sub/b.gno", "package sub\n\nfunc B() {}\n")
+	name := filepath.Base(root)
+
+	x := gno.NewIndex("", root, nil)
+	require.NoError(t, x.Load())
+	assert.Equal(t, []string{name + ": A", "sub: B"}, pkgs(x))
+
+	// changed file
+	write("sub/b.gno", "package sub\n\nfunc B2() {}\n")
+	require.NoError(t, x.Update(subFile))
+	assert.Equal(t, []string{name + ": A", "sub: B2"}, pkgs(x))
+
+	// new file
+	require.NoError(t, x.Update(write("sub/c.gno", "package sub\n\nfunc C() {}\n")))
+	assert.Equal(t, []string{name + ": A", "sub: B2 C"}, pkgs(x))
+
+	// new gno.mod
+	require.NoError(t, x.Update(write("gno.mod", "module gno.land/p/demo/foo\n")))
+	assert.Equal(t, []string{"gno.land/p/demo/foo: A", "gno.land/p/demo/foo/sub: B2 C"}, pkgs(x))
+
+	// deleted file and directory
+	require.NoError(t, os.Remove(subFile))
+	require.NoError(t, x.Update(subFile))
+	assert.Equal(t, []string{"gno.land/p/demo/foo: A", "gno.land/p/demo/foo/sub: C"}, pkgs(x))
+	require.NoError(t, os.RemoveAll(filepath.Join(root, "sub")))
+	require.NoError(t, x.Update(filepath.Join(root, "sub")))
+	assert.Equal(t, []string{"gno.land/p/demo/foo: A"}, pkgs(x))
+
+	// ignored files
+	require.NoError(t, x.Update(write("a_test.gno", "package foo\n\nfunc TestA() {}\n")))
+	require.NoError(t, x.Update(write(".hidden/d.gno", "package d\n\nfunc D() {}\n")))
+	assert.Equal(t, []string{"gno.land/p/demo/foo: A"}, pkgs(x))
+}
@@ -0,0 +1,130 @@
+package gno
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// ModFileName is the name of the file declaring a Gno module.
+const ModFileName = "gno.mod"
+
+// ReadModFile parses the gno.mod file of dir. The replace directives are
+// ignored if the file isn't valid.
+func ReadModFile(dir string) (*modfile.File, error) {
+	filename := filepath.Join(dir, ModFileName)
+	bz, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if mod, err := modfile.Parse(filename, bz, nil); err == nil {
+		return mod, nil
+	}
+	return modfile.ParseLax(filename, bz, nil)
+}
+
+// FindModFile returns the closest gno.mod file in dir or its parents, and the
+// directory containing it. It returns false if there's no gno.mod file.
+func FindModFile(dir string) (*modfile.File, string, bool) {
+	for d := dir; ; d = filepath.Dir(d) {
+		if mod, err := ReadModFile(d); err == nil && mod.Module != nil {
+			return mod, d, true
+		}
+		if filepath.Dir(d) == d {
+			return nil, "", false
+		}
+	}
+}
+
+// PkgPath returns the package path of dir, from the module path of the
+// closest gno.mod file in dir or its parents. It returns false if there's no
+// gno.mod file.
+func PkgPath(dir string) (string, bool) {
+	mod, modDir, ok := FindModFile(dir)
+	if !ok {
+		return "", false
+	}
+	rel, err := filepath.Rel(modDir, dir)
+	if err != nil {
+		return "", false
+	}
+	return path.Join(mod.Module.Mod.Path, filepath.ToSlash(rel)), true
+}
+
+// ImportPath returns the import path of the package in dir, a directory of
+// rootDir: its package path if it belongs to a module, otherwise its path
+// relative to rootDir, or the name of rootDir for rootDir itself.
+func ImportPath(rootDir, dir string) string {
+	if pkgPath, ok := PkgPath(dir); ok {
+		return pkgPath
+	}
+	rel, err := filepath.Rel(rootDir, dir)
+	if err != nil {
+		return filepath.ToSlash(dir)
+	}
+	if rel == "." {
+		return filepath.Base(rootDir)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// IsDraft returns true if mod is the gno.mod file of a draft module, which is
+// flagged with a "// Draft" comment at the top of the file.
+func IsDraft(mod *modfile.File) bool {
+	if mod.Syntax == nil || len(mod.Syntax.Stmt) == 0 {
+		return false
+	}
+	before := mod.Syntax.Stmt[0].Comment().Before
+	return len(before) == 1 && strings.TrimSpace(strings.TrimPrefix(before[0].Token, "//")) == "Draft"
+}
+
+// ResolveModImport returns the directory of the package imported with
+// importPath, according to the replace directives of mod, whose gno.mod file
+// is in modDir. If the package is replaced by another module, the new import
+// path is returned instead, with an empty dir.
+func ResolveModImport(mod *modfile.File, modDir, importPath string) (dir, newPath string) {
+	for _, r := range mod.Replace {
+		rest, ok := strings.CutPrefix(importPath, r.Old.Path)
+		if !ok || (rest != "" && rest[0] != '/') {
+			continue
+		}
+		if r.New.Version == "" && modfile.IsDirectoryPath(r.New.Path) {
+			// replaced by a local directory
+			dir := r.New.Path
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(modDir, dir)
+			}
+			return filepath.Join(dir, filepath.FromSlash(rest)), ""
+		}
+		return "", r.New.Path + rest
+	}
+	return "", importPath
+}
+
+// Requires returns true if the package imported with importPath belongs to a
+// module required by mod.
+func Requires(mod *modfile.File, importPath string) bool {
+	for _, r := range mod.Require {
+		if importPath == r.Mod.Path || strings.HasPrefix(importPath, r.Mod.Path+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// ModCacheDir returns the directory where the gno tool downloads the required
+// modules: $GNOHOME/pkg/mod.
+func ModCacheDir() string {
+	home := os.Getenv("GNOHOME")
+	if home == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return ""
+		}
+		home = filepath.Join(dir, "gno")
+	}
+	return filepath.Join(home, "pkg", "mod")
+}
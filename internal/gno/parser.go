@@ -12,7 +12,13 @@ import (
 type Package struct {
 	Name       string
 	ImportPath string
-	Symbols    []Symbol
+	// Doc is the package documentation.
+	Doc string `json:",omitempty"`
+	// Dir is the directory of the package, it's empty in the stdlib index.
+	Dir string `json:",omitempty"`
+	// Draft is true if the package belongs to a draft module.
+	Draft   bool `json:",omitempty"`
+	Symbols []Symbol
 }
 
 type Symbol struct {
@@ -21,9 +27,26 @@ type Symbol struct {
 	Signature string `json:",omitempty"`
 	Kind      string
 	Recv      string `json:",omitempty"`
+	// PtrRecv is true if the method has a pointer receiver.
+	PtrRecv bool `json:",omitempty"`
+	// TypeParams contains the type parameters of a generic type or function,
+	// like "T any".
+	TypeParams []string `json:",omitempty"`
 	// Fields contains fields and methods
 	Fields []Symbol `json:",omitempty"`
 	Type   string   `json:",omitempty"`
+	// Results contains the result types of a function.
+	Results []string `json:",omitempty"`
+	// Pos is the position of the declaration.
+	Pos *Position `json:",omitempty"`
+}
+
+// Position is the position of a declaration in the files of its package.
+type Position struct {
+	// File is the name of the file, relative to the package directory.
+	File   string
+	Line   int
+	Column int
 }
 
 // ParsePackages parses gno files in rootDir and sub-directories, and returns
@@ -31,40 +54,11 @@ type Symbol struct {
 // If wd is provided and is different from the file being parsed, then only
 // public symbols are returned.
 func ParsePackages(wd, rootDir string) ([]Package, error) {
-	dirs, err := getDirs(rootDir)
-	if err != nil {
+	x := NewIndex(wd, rootDir, nil)
+	if err := x.Load(); err != nil {
 		return nil, err
 	}
-	pkgs := []Package{}
-	for _, dir := range dirs {
-		files, err := getFiles(dir)
-		if err != nil {
-			return nil, err
-		}
-		// convert to import path:
-		// get path relative to dir, and convert separators to slashes.
-		rel, err := filepath.Rel(rootDir, dir)
-		if err != nil {
-			return nil, err
-		}
-		ip := strings.ReplaceAll(rel, string(filepath.Separator), "/")
-		pkg := Package{
-			Name:       filepath.Base(dir),
-			ImportPath: ip,
-		}
-		for _, file := range files {
-			symbols, err := getSymbols(wd, file)
-			if err != nil {
-				return nil, err
-			}
-			pkg.Symbols = append(pkg.Symbols, symbols...)
-		}
-		if len(pkg.Symbols) > 0 {
-			pkgs = append(pkgs, pkg)
-		}
-	}
-
-	return pkgs, nil
+	return x.Packages(), nil
 }
 
 // getDirs returns all directories inside path, ignoring hidden directories.
@@ -74,9 +68,13 @@ func getDirs(path string) ([]string, error) {
 		if err != nil {
 			return err
 		}
-		if d.IsDir() && filepath.Base(dir)[0] != '.' { // avoid hidden dir
-			dirs = append(dirs, dir)
+		if !d.IsDir() {
+			return nil
+		}
+		if dir != path && filepath.Base(dir)[0] == '.' { // avoid hidden dir
+			return filepath.SkipDir
 		}
+		dirs = append(dirs, dir)
 		return nil
 	})
 	return dirs, err
@@ -87,49 +85,45 @@ func getDirs(path string) ([]string, error) {
 // TODO parse test files inside wd so completion can work with symbols created
 // in test files.
 func getFiles(path string) ([]string, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
 	var files []string
-
-	err := filepath.WalkDir(path, func(file string, d os.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		if d.IsDir() {
-			return nil
-		}
-		if filepath.Dir(file) != path {
-			return nil
+	for _, e := range entries {
+		if !e.IsDir() && isGnoFile(e.Name()) {
+			files = append(files, filepath.Join(path, e.Name()))
 		}
-		if strings.Contains(file, "_test") {
-			return nil
-		}
-		ext := filepath.Ext(file)
-		if ext != ".gno" {
-			return nil
-		}
-		files = append(files, file)
-		return nil
-	})
-	return files, err
+	}
+	return files, nil
 }
 
-// getSymbols returns all symbols found in filename. If wd is different than
-// filname path, only public symbols are returned.
-func getSymbols(wd string, filename string) ([]Symbol, error) {
-	var symbols []Symbol
-
-	// Create a FileSet to work with.
+// parseSymbols parses src, the content of filename, and returns the package
+// name, the package documentation and the symbols of the file. Files with
+// syntax errors are indexed from their partial AST.
+func parseSymbols(wd, filename string, src []byte) (cacheEntry, error) {
 	fset := token.NewFileSet()
-
-	// Parse the file and create an AST.
-	file, _ := parser.ParseFile(fset, filename, nil, parser.ParseComments)
-	// fmt.Println("PARSEFILE", err)
-
-	bsrc, err := os.ReadFile(filename)
-	if err != nil {
-		return nil, err
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if file == nil {
+		return cacheEntry{}, err
+	}
+	entry := cacheEntry{Doc: strings.TrimSpace(file.Doc.Text())}
+	if file.Name != nil && file.Name.Name != "_" {
+		// "_" is the name of an invalid package clause
+		entry.Name = file.Name.Name
 	}
-	text := string(bsrc)
+	entry.Symbols = getSymbols(wd, filename, file, source{file: fset.File(file.FileStart), text: string(src)})
+	return entry, nil
+}
+
+// getSymbols returns all symbols found in file, the AST of filename whose
+// source is text. If wd is different than filename path, only public symbols
+// are returned.
+func getSymbols(wd, filename string, file *ast.File, text source) []Symbol {
+	var symbols []Symbol
 
+	// the value specs are trimmed by ast.FileExports, keep them before
+	specs := valueSpecs(file)
 	isCurrentDir := wd == filepath.Dir(filename)
 	if !isCurrentDir {
 		// Trim AST to exported declarations only if not in working dir
@@ -155,27 +149,25 @@ func getSymbols(wd string, filename string) ([]Symbol, error) {
 		} else {
 			nestedCount++
 		}
-		var found *Symbol
+		var found []Symbol
 
 		// fmt.Println("NODE", filename, nestedCount, spew.Sdump(n))
 		switch n := n.(type) {
 		case *ast.FuncDecl:
-			found = function(n, text)
+			found = []Symbol{*function(n, text)}
 		case *ast.GenDecl:
-			found = declaration(n, text)
+			found = declaration(n, specs, text)
 		case *ast.AssignStmt:
 			if n.Tok == token.DEFINE {
 				// new variable declaration with :=
 				found = assignment(n, text)
 			}
-		case *ast.ValueSpec:
-			found = variable(n, text)
 		}
 
-		if found != nil {
-			if isCurrentDir || isExported(found.Name) {
+		for _, sym := range found {
+			if isCurrentDir || isExported(sym.Name) {
 				// append only if current directory or node is exported
-				symbols = append(symbols, *found)
+				symbols = append(symbols, sym)
 			}
 		}
 
@@ -196,103 +188,373 @@ func getSymbols(wd string, filename string) ([]Symbol, error) {
 		}
 	}
 
-	return symbols, nil
+	return symbols
+}
+
+// source gives access to the text of the nodes of a parsed file.
+type source struct {
+	file *token.File
+	text string
+}
+
+// of returns the text of n.
+func (s source) of(n ast.Node) string {
+	return s.text[s.file.Offset(n.Pos()):s.file.Offset(n.End())]
+}
+
+// pos returns the position of n.
+func (s source) pos(n ast.Node) *Position {
+	p := s.file.Position(n.Pos())
+	return &Position{File: filepath.Base(p.Filename), Line: p.Line, Column: p.Column}
+}
+
+// fieldTypes returns the type of each name of fl, or of each field if they
+// have no name, like "T any" or "error" if withNames is false.
+func (s source) fieldTypes(fl *ast.FieldList, withNames bool) []string {
+	if fl == nil {
+		return nil
+	}
+	var types []string
+	for _, f := range fl.List {
+		typ := s.of(f.Type)
+		if len(f.Names) == 0 {
+			types = append(types, typ)
+		}
+		for _, name := range f.Names {
+			if withNames {
+				types = append(types, name.Name+" "+typ)
+			} else {
+				types = append(types, typ)
+			}
+		}
+	}
+	return types
+}
+
+// DeclSymbol returns the symbol declared by the first declaration node of
+// path, as returned by astutil.PathEnclosingInterval, or nil if there's none.
+// text is the content of the file, parsed in fset.
+func DeclSymbol(fset *token.FileSet, text string, path []ast.Node) *Symbol {
+	if len(path) == 0 {
+		return nil
+	}
+	src := source{file: fset.File(path[0].Pos()), text: text}
+	for i, n := range path {
+		switch n := n.(type) {
+		case *ast.Field:
+			return symbolNamed(fields(n, src), path[0])
+		case *ast.FuncDecl:
+			return function(n, src)
+		case *ast.TypeSpec:
+			var doc *ast.CommentGroup
+			if i+1 < len(path) {
+				if d, ok := path[i+1].(*ast.GenDecl); ok {
+					doc = d.Doc
+				}
+			}
+			return typeSpec(n, doc, src)
+		case *ast.ValueSpec:
+			decl, ok := path[i+1].(*ast.GenDecl)
+			if !ok {
+				return nil
+			}
+			file, _ := path[len(path)-1].(*ast.File)
+			return symbolNamed(values(decl, n, valueSpecs(file)[n], src), path[0])
+		case *ast.AssignStmt:
+			if n.Tok == token.DEFINE {
+				return symbolNamed(assignment(n, src), path[0])
+			}
+		case *ast.BlockStmt, *ast.File:
+			// went outside of the declaration
+			return nil
+		}
+	}
+	return nil
 }
 
-func declaration(n *ast.GenDecl, source string) *Symbol {
+// symbolNamed returns the symbol of syms declared by n, if it's an identifier,
+// or the first one.
+func symbolNamed(syms []Symbol, n ast.Node) *Symbol {
+	if len(syms) == 0 {
+		return nil
+	}
+	if id, ok := n.(*ast.Ident); ok {
+		for i := range syms {
+			if syms[i].Name == id.Name {
+				return &syms[i]
+			}
+		}
+	}
+	return &syms[0]
+}
+
+// declaration returns the symbols of all the names declared by n.
+func declaration(n *ast.GenDecl, specs map[*ast.ValueSpec]valueSpec, source source) []Symbol {
+	var syms []Symbol
 	for _, spec := range n.Specs {
-		switch t := spec.(type) { //nolint:gocritic
+		switch s := spec.(type) {
 		case *ast.TypeSpec:
-			typ, fields := typeFromNode(t.Type, source)
-			return &Symbol{
-				Name:      t.Name.Name,
-				Doc:       strings.TrimSpace(n.Doc.Text()),
-				Signature: strings.Split(source[t.Pos()-1:t.End()-1], " {")[0],
-				Kind:      typeName(*t),
-				Type:      typ,
-				Fields:    fields,
+			doc := s.Doc
+			if doc == nil {
+				doc = n.Doc
 			}
+			syms = append(syms, *typeSpec(s, doc, source))
+		case *ast.ValueSpec:
+			syms = append(syms, values(n, s, specs[s], source)...)
 		}
 	}
+	return syms
+}
 
-	return nil
+// valueSpec contains the names and the values of a var or const spec, and its
+// type. In const blocks, the type and the values of a spec without them are
+// those of the previous spec, like in iota enums.
+type valueSpec struct {
+	names    []*ast.Ident
+	typ      ast.Expr
+	values   []ast.Expr
+	implicit bool
 }
 
-func function(n *ast.FuncDecl, source string) *Symbol {
-	var kind, recv, retType string
-	if n.Recv != nil {
-		recv, _ = typeFromNode(n.Recv.List[0].Type, source)
-		kind = "method"
-	} else {
-		kind = "func"
+// valueSpecs returns the value specs of file, indexed by their node.
+func valueSpecs(file *ast.File) map[*ast.ValueSpec]valueSpec {
+	specs := make(map[*ast.ValueSpec]valueSpec)
+	if file == nil {
+		return specs
 	}
-	retType, params := typeFromNode(n.Type, source)
-	return &Symbol{
-		Name:      n.Name.Name,
-		Doc:       n.Doc.Text(),
-		Signature: strings.Split(source[n.Pos()-1:n.End()-1], " {")[0],
-		Kind:      kind,
-		Fields:    params,
-		Recv:      recv,
-		Type:      retType,
+	ast.Inspect(file, func(n ast.Node) bool {
+		decl, ok := n.(*ast.GenDecl)
+		if !ok || decl.Tok != token.VAR && decl.Tok != token.CONST {
+			return true
+		}
+		var prev valueSpec
+		for _, spec := range decl.Specs {
+			s, ok := spec.(*ast.ValueSpec)
+			if !ok {
+				continue
+			}
+			vs := valueSpec{
+				names:  append([]*ast.Ident(nil), s.Names...),
+				typ:    s.Type,
+				values: append([]ast.Expr(nil), s.Values...),
+			}
+			if decl.Tok == token.CONST && vs.typ == nil && len(vs.values) == 0 {
+				vs.typ, vs.values, vs.implicit = prev.typ, prev.values, true
+			}
+			specs[s] = vs
+			prev = vs
+		}
+		return true
+	})
+	return specs
+}
+
+// values returns the symbols of the names of s, a spec of decl. vs contains
+// the names and the values of s before the file was trimmed, if they are
+// known.
+func values(decl *ast.GenDecl, s *ast.ValueSpec, vs valueSpec, source source) []Symbol {
+	if vs.names == nil {
+		vs = valueSpec{names: s.Names, typ: s.Type, values: s.Values}
+	}
+	kind := "var"
+	if decl.Tok == token.CONST {
+		kind = "const"
+	}
+	doc := s.Doc
+	if doc == nil && !decl.Lparen.IsValid() {
+		doc = decl.Doc
+	}
+	if doc == nil {
+		// trailing comment, common in enums
+		doc = s.Comment
+	}
+	var syms []Symbol
+	for i, name := range vs.names {
+		if name.Name == "_" {
+			continue
+		}
+		var (
+			typ    string
+			fields []Symbol
+			value  ast.Expr
+		)
+		if len(vs.values) == len(vs.names) {
+			value = vs.values[i]
+		}
+		switch {
+		case vs.typ != nil:
+			typ, fields = typeFromNode(vs.typ, source)
+		case value != nil:
+			typ, fields = typeFromNode(value, source)
+		}
+		sig := source.of(s)
+		if len(vs.names) > 1 || vs.implicit {
+			// the declaration of this name only
+			sig = name.Name
+			if vs.typ != nil {
+				sig += " " + source.of(vs.typ)
+			}
+			if value != nil && !vs.implicit {
+				sig += " = " + source.of(value)
+			}
+		}
+		syms = append(syms, Symbol{
+			Name:      name.Name,
+			Doc:       strings.TrimSpace(doc.Text()),
+			Signature: sig,
+			Kind:      kind,
+			Type:      typ,
+			Fields:    fields,
+			Pos:       source.pos(name),
+		})
 	}
+	return syms
 }
 
-func assignment(n *ast.AssignStmt, source string) *Symbol {
-	typ, fields := typeFromNode(n.Rhs[0], source)
+func typeSpec(t *ast.TypeSpec, doc *ast.CommentGroup, source source) *Symbol {
+	typ, fields := typeFromNode(t.Type, source)
 	return &Symbol{
-		Name:      n.Lhs[0].(*ast.Ident).Name,
-		Signature: source[n.Pos()-1 : n.End()-1],
-		Kind:      "var",
-		Type:      typ,
-		Fields:    fields,
+		Name:       t.Name.Name,
+		Doc:        strings.TrimSpace(doc.Text()),
+		Signature:  strings.Split(source.of(t), " {")[0],
+		Kind:       typeName(*t),
+		TypeParams: source.fieldTypes(t.TypeParams, true),
+		Type:       typ,
+		Fields:     fields,
+		Pos:        source.pos(t.Name),
 	}
 }
 
-func variable(n *ast.ValueSpec, source string) *Symbol {
-	typ, fields := typeFromNode(n, source)
+func function(n *ast.FuncDecl, source source) *Symbol {
+	var (
+		kind, recv, retType string
+		ptrRecv             bool
+	)
+	if n.Recv != nil {
+		recv, _ = typeFromNode(n.Recv.List[0].Type, source)
+		_, ptrRecv = n.Recv.List[0].Type.(*ast.StarExpr)
+		kind = "method"
+	} else {
+		kind = "func"
+	}
+	retType, params := typeFromNode(n.Type, source)
 	return &Symbol{
-		Name:      n.Names[0].Name,
-		Doc:       strings.TrimSpace(n.Doc.Text()),
-		Signature: source[n.Pos()-1 : n.End()-1],
-		Kind:      "var",
-		Type:      typ,
-		Fields:    fields,
+		Name:       n.Name.Name,
+		Doc:        n.Doc.Text(),
+		Signature:  strings.Split(source.of(n), " {")[0],
+		Kind:       kind,
+		Fields:     params,
+		Recv:       recv,
+		PtrRecv:    ptrRecv,
+		TypeParams: source.fieldTypes(n.Type.TypeParams, true),
+		Type:       retType,
+		Results:    source.fieldTypes(n.Type.Results, false),
+		Pos:        source.pos(n.Name),
 	}
 }
 
-func symbolsFromFieldList(fl *ast.FieldList, source string) (syms []Symbol) {
-	for _, f := range fl.List {
-		typ, subfields := typeFromNode(f.Type, source)
-		var name string
-		if len(f.Names) > 0 {
-			name = f.Names[0].Name
-		} else {
-			// f is an embedded struct, use type name as the name.
-			name = typ
+// assignment returns the symbols of the variables declared by n.
+func assignment(n *ast.AssignStmt, source source) []Symbol {
+	var syms []Symbol
+	for i, lhs := range n.Lhs {
+		id, ok := lhs.(*ast.Ident)
+		if !ok || id.Name == "_" {
+			continue
 		}
-		kind := "field"
-		if _, ok := f.Type.(*ast.FuncType); ok {
-			kind = "method"
+		var (
+			typ    string
+			fields []Symbol
+			sig    = source.of(n)
+		)
+		if len(n.Rhs) == len(n.Lhs) {
+			typ, fields = typeFromNode(n.Rhs[i], source)
+			if len(n.Lhs) > 1 {
+				sig = id.Name + " := " + source.of(n.Rhs[i])
+			}
 		}
 		syms = append(syms, Symbol{
-			Name:      name,
-			Doc:       strings.TrimSpace(f.Doc.Text()),
-			Signature: source[f.Pos()-1 : f.End()-1],
-			Kind:      kind,
+			Name:      id.Name,
+			Signature: sig,
+			Kind:      "var",
 			Type:      typ,
-			Fields:    subfields,
+			Fields:    fields,
+			Pos:       source.pos(id),
 		})
 	}
+	return syms
+}
+
+func symbolsFromFieldList(fl *ast.FieldList, source source) (syms []Symbol) {
+	for _, f := range fl.List {
+		syms = append(syms, fields(f, source)...)
+	}
 	return
 }
 
-func typeFromNode(x ast.Node, source string) (string, []Symbol) {
+// fields returns the symbols of all the names of f, like X and Y for
+// "X, Y int".
+func fields(f *ast.Field, source source) []Symbol {
+	typ, subfields := typeFromNode(f.Type, source)
+	kind := "field"
+	var results []string
+	if ft, ok := f.Type.(*ast.FuncType); ok {
+		kind = "method"
+		results = source.fieldTypes(ft.Results, false)
+	}
+	sym := Symbol{
+		Doc:       strings.TrimSpace(f.Doc.Text()),
+		Signature: source.of(f),
+		Kind:      kind,
+		Type:      typ,
+		Fields:    subfields,
+		Results:   results,
+		Pos:       source.pos(f),
+	}
+	if len(f.Names) == 0 {
+		// f is an embedded struct, use type name as the name.
+		sym.Name = typ[strings.LastIndex(typ, ".")+1:]
+		return []Symbol{sym}
+	}
+	if len(f.Names) == 1 {
+		sym.Name = f.Names[0].Name
+		return []Symbol{sym}
+	}
+	// each name has its own signature, like "Y int"
+	signature := source.of(f.Type)
+	if f.Tag != nil {
+		signature += " " + f.Tag.Value
+	}
+	syms := make([]Symbol, len(f.Names))
+	for i, name := range f.Names {
+		syms[i] = sym
+		syms[i].Name = name.Name
+		syms[i].Signature = name.Name + " " + signature
+		syms[i].Pos = source.pos(name)
+	}
+	return syms
+}
+
+func typeFromNode(x ast.Node, source source) (string, []Symbol) {
 	switch x := x.(type) {
 	case *ast.Ident:
+		if x.Name == "iota" {
+			return "int", nil
+		}
 		return x.Name, nil
+	case *ast.BinaryExpr:
+		// like 1 << iota
+		return typeFromNode(x.X, source)
 	case *ast.StarExpr:
 		return typeFromNode(x.X, source)
+	case *ast.ParenExpr:
+		return typeFromNode(x.X, source)
+	case *ast.IndexExpr:
+		// instantiated generic type
+		return typeFromNode(x.X, source)
+	case *ast.IndexListExpr:
+		return typeFromNode(x.X, source)
+	case *ast.SelectorExpr, *ast.ArrayType, *ast.MapType, *ast.ChanType, *ast.Ellipsis:
+		return source.of(x), nil
 	case *ast.ValueSpec:
 		if x.Type != nil {
 			return typeFromNode(x.Type, source)
@@ -311,9 +573,9 @@ func typeFromNode(x ast.Node, source string) (string, []Symbol) {
 		return "", symbolsFromFieldList(x.Fields, source)
 	case *ast.FuncType:
 		var retType string
-		if x.Results != nil && len(x.Results.List) == 1 {
+		if x.Results != nil && len(x.Results.List) == 1 && len(x.Results.List[0].Names) <= 1 {
 			// Store retType only if there's only one
-			retType = x.Results.List[0].Type.(*ast.Ident).Name
+			retType, _ = typeFromNode(x.Results.List[0].Type, source)
 		}
 		return retType, symbolsFromFieldList(x.Params, source)
 	case *ast.InterfaceType:
@@ -0,0 +1,103 @@
+package gno
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+)
+
+// SchemaVersion is the version of the format of the indexes generated by
+// cmd/gen. It must be incremented when Package or Symbol change, so outdated
+// indexes are detected.
+//
+// Schema 1 is a bare list of packages, without positions, receiver and
+// generics information. Schema 2 adds them, and the index header.
+const SchemaVersion = 2
+
+// IndexFile is an index of packages generated by cmd/gen.
+type IndexFile struct {
+	// Schema is the SchemaVersion of the index.
+	Schema int
+	// Version is the gno version the index was generated from.
+	Version  string `json:",omitempty"`
+	Packages []Package
+}
+
+// NewIndexFile returns the index of pkgs, sorted by import path so the
+// output is deterministic.
+func NewIndexFile(version string, pkgs []Package) *IndexFile {
+	pkgs = append([]Package(nil), pkgs...)
+	sort.SliceStable(pkgs, func(i, j int) bool { return pkgs[i].ImportPath < pkgs[j].ImportPath })
+	return &IndexFile{Schema: SchemaVersion, Version: version, Packages: pkgs}
+}
+
+// WriteGob writes the index in the gob format.
+func (f *IndexFile) WriteGob(w io.Writer) error {
+	return gob.NewEncoder(w).Encode(f)
+}
+
+// WriteJSON writes the index in the JSON format.
+func (f *IndexFile) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", " ")
+	return enc.Encode(f)
+}
+
+// DecodeIndex decodes an index written by WriteGob or WriteJSON. Indexes of
+// schema 1 are supported too, but those of a newer schema than SchemaVersion
+// are rejected.
+func DecodeIndex(data []byte) (*IndexFile, error) {
+	var (
+		f       IndexFile
+		pkgs    []Package
+		err     error
+		trimmed = bytes.TrimSpace(data)
+	)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		// schema 1
+		if err := json.Unmarshal(data, &pkgs); err != nil {
+			return nil, fmt.Errorf("decode index: %w", err)
+		}
+		return &IndexFile{Schema: 1, Packages: pkgs}, nil
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		err = json.Unmarshal(data, &f)
+	default:
+		err = gob.NewDecoder(bytes.NewReader(data)).Decode(&f)
+		if err != nil && gob.NewDecoder(bytes.NewReader(data)).Decode(&pkgs) == nil {
+			// schema 1
+			return &IndexFile{Schema: 1, Packages: pkgs}, nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decode index: %w", err)
+	}
+	if f.Schema < 1 || f.Schema > SchemaVersion {
+		return nil, fmt.Errorf("decode index: unsupported schema %d, expected at most %d", f.Schema, SchemaVersion)
+	}
+	return &f, nil
+}
+
+// ReadIndexFile reads the index written in the file filename, in the gob or
+// the JSON format.
+func ReadIndexFile(filename string) (*IndexFile, error) {
+	bz, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeIndex(bz)
+}
+
+var reUnsafe = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// IndexName returns the name of the index file of the gno version, without
+// extension.
+func IndexName(version string) string {
+	return reUnsafe.ReplaceAllString(version, "_")
+}
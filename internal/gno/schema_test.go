@@ -0,0 +1,108 @@
+package gno_test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jdkato/gnols/internal/gno"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexFile(t *testing.T) {
+	pkgs := []gno.Package{
+		{Name: "ufmt", ImportPath: "gno.land/p/demo/ufmt"},
+		{
+			Name:       "avl",
+			ImportPath: "gno.land/p/demo/avl",
+			Symbols: []gno.Symbol{{
+				Name:      "Size",
+				Signature: "func (t *Tree) Size() int",
+				Kind:      "method",
+				Recv:      "Tree",
+				PtrRecv:   true,
+				Results:   []string{"int"},
+				Pos:       &gno.Position{File: "tree.gno", Line: 12, Column: 16},
+			}},
+		},
+	}
+	index := gno.NewIndexFile("v1", pkgs)
+	assert.Equal(t, gno.SchemaVersion, index.Schema)
+	assert.Equal(t, "gno.land/p/demo/avl", index.Packages[0].ImportPath, "packages must be sorted")
+
+	for name, write := range map[string]func(*bytes.Buffer) error{
+		"gob":  func(b *bytes.Buffer) error { return index.WriteGob(b) },
+		"json": func(b *bytes.Buffer) error { return index.WriteJSON(b) },
+	} {
+		var buf bytes.Buffer
+		require.NoError(t, write(&buf), name)
+		decoded, err := gno.DecodeIndex(buf.Bytes())
+		require.NoError(t, err, name)
+		assert.Equal(t, index, decoded, name)
+	}
+
+	// the JSON output is deterministic
+	var b1, b2 bytes.Buffer
+	require.NoError(t, index.WriteJSON(&b1))
+	require.NoError(t, gno.NewIndexFile("v1", []gno.Package{pkgs[1], pkgs[0]}).WriteJSON(&b2))
+	assert.Equal(t, b1.String(), b2.String())
+}
+
+func TestDecodeIndexSchema(t *testing.T) {
+	pkgs := []gno.Package{{Name: "strings", ImportPath: "strings"}}
+
+	// schema 1, a bare list of packages
+	var buf bytes.Buffer
+	require.NoError(t, gob.NewEncoder(&buf).Encode(pkgs))
+	index, err := gno.DecodeIndex(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, &gno.IndexFile{Schema: 1, Packages: pkgs}, index)
+
+	bz, err := json.Marshal(pkgs)
+	require.NoError(t, err)
+	index, err = gno.DecodeIndex(bz)
+	require.NoError(t, err)
+	assert.Equal(t, &gno.IndexFile{Schema: 1, Packages: pkgs}, index)
+
+	// newer schema
+	buf.Reset()
+	future := gno.IndexFile{Schema: gno.SchemaVersion + 1, Packages: pkgs}
+	require.NoError(t, future.WriteGob(&buf))
+	_, err = gno.DecodeIndex(buf.Bytes())
+	assert.ErrorContains(t, err, "unsupported schema")
+
+	// invalid data
+	_, err = gno.DecodeIndex([]byte("not an index"))
+	assert.Error(t, err)
+}
+
+func TestReadIndexFile(t *testing.T) {
+	dir := t.TempDir()
+	index := gno.NewIndexFile("v1.0.0", []gno.Package{{Name: "strings", ImportPath: "strings"}})
+	for _, format := range []string{"gob", "json"} {
+		filename := filepath.Join(dir, gno.IndexName(index.Version)+"."+format)
+		var buf bytes.Buffer
+		if format == "gob" {
+			require.NoError(t, index.WriteGob(&buf))
+		} else {
+			require.NoError(t, index.WriteJSON(&buf))
+		}
+		require.NoError(t, os.WriteFile(filename, buf.Bytes(), os.ModePerm))
+
+		decoded, err := gno.ReadIndexFile(filename)
+		require.NoError(t, err, format)
+		assert.Equal(t, index, decoded, format)
+	}
+
+	_, err := gno.ReadIndexFile(filepath.Join(dir, "missing.gob"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestIndexName(t *testing.T) {
+	assert.Equal(t, "v0.1.0", gno.IndexName("v0.1.0"))
+	assert.Equal(t, "develop.abc123__dirty_", gno.IndexName("develop.abc123 (dirty)"))
+}
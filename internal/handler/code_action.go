@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+func (h *handler) handleCodeAction(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params protocol.CodeActionParams
+	if err := readParams(req, &params); err != nil {
+		return replyErr(ctx, reply, err)
+	}
+
+	doc, ok := h.documents.Get(params.TextDocument.URI)
+	if !ok {
+		return replyNoDocFound(ctx, reply, params.TextDocument.URI)
+	}
+
+	actions := []protocol.CodeAction{}
+	c, err := h.newCursor(doc, doc.PositionToOffset(params.Range.Start))
+	if err != nil {
+		slog.Error("code action", "err", err)
+		return reply(ctx, actions, nil)
+	}
+	if action, ok := c.fillStruct(params.TextDocument.URI); ok {
+		actions = append(actions, action)
+	}
+	return reply(ctx, actions, nil)
+}
@@ -2,17 +2,40 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"go/ast"
 	"log/slog"
+	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	"go.lsp.dev/jsonrpc2"
 	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
 
+	"github.com/jdkato/gnols/internal/gno"
 	"github.com/jdkato/gnols/internal/store"
 )
 
+// Kinds of code lenses resolved by `codeLens/resolve`.
+const (
+	lensTest       = "test"
+	lensReferences = "references"
+)
+
+// codeLensData is attached to the code lenses returned without command, it
+// contains what `codeLens/resolve` needs to build the command.
+type codeLensData struct {
+	Kind string `json:"kind"`
+	Path string `json:"path"`
+	Name string `json:"name"`
+	// Position of the function name, used to find the references.
+	Position *protocol.Position `json:"position,omitempty"`
+}
+
 var (
 	testRe      = regexp.MustCompile("^Test[^a-z]")
 	benchmarkRe = regexp.MustCompile("^Benchmark[^a-z]")
@@ -40,10 +63,23 @@ func (h *handler) handleCodeLens(ctx context.Context, reply jsonrpc2.Replier, re
 	}
 
 	items := []protocol.CodeLens{}
-	if !strings.HasSuffix(doc.Path, "_test.gno") {
+	if doc.Pgf == nil {
 		return reply(ctx, items, nil)
 	}
-	if doc.Pgf == nil {
+	if !strings.HasSuffix(doc.Path, "_test.gno") {
+		if h.clientCommands["gnols.references"] {
+			items = append(items, addReferencesCmds(doc)...)
+		}
+		if fn := mainFunc(doc); fn != nil {
+			items = append(items, protocol.CodeLens{
+				Range: doc.RangeOf(fn.Name.Pos(), fn.Name.End()),
+				Command: &protocol.Command{
+					Title:     "run",
+					Command:   "gnols.run",
+					Arguments: []interface{}{doc.Path},
+				},
+			})
+		}
 		return reply(ctx, items, nil)
 	}
 
@@ -57,13 +93,139 @@ func (h *handler) handleCodeLens(ctx context.Context, reply jsonrpc2.Replier, re
 		len(tAndB.Benchmarks),
 	)
 
-	items = append(items, addTestCmds(doc.Path, tAndB)...)
+	items = append(items, addTestCmds(doc.Path, tAndB, h.clientCommands["gnols.debugTest"])...)
 	items = append(items, addBenchCmds(doc.Path, tAndB)...)
 
 	return reply(ctx, items, nil)
 }
 
-func addTestCmds(path string, tAndB testFns) []protocol.CodeLens {
+func (h *handler) handleCodeLensResolve(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var lens protocol.CodeLens
+	if err := readParams(req, &lens); err != nil {
+		return replyErr(ctx, reply, err)
+	}
+	lens, err := h.resolveCodeLens(ctx, lens)
+	if err != nil {
+		return replyErr(ctx, reply, err)
+	}
+	return reply(ctx, lens, nil)
+}
+
+func (h *handler) resolveCodeLens(ctx context.Context, lens protocol.CodeLens) (protocol.CodeLens, error) {
+	if lens.Command != nil || lens.Data == nil {
+		// nothing to resolve
+		return lens, nil
+	}
+	// lens.Data is decoded as a map, convert it back to codeLensData
+	bz, err := json.Marshal(lens.Data)
+	if err != nil {
+		return lens, err
+	}
+	var data codeLensData
+	if err := json.Unmarshal(bz, &data); err != nil {
+		return lens, fmt.Errorf("invalid code lens data: %w", err)
+	}
+	slog.Info("code_lens resolve", "data", data)
+
+	switch data.Kind {
+	case lensTest:
+		title := "run test"
+		h.testResultsMu.Lock()
+		res, ok := h.testResults[testKey(filepath.Dir(data.Path), data.Name)]
+		h.testResultsMu.Unlock()
+		if ok {
+			status := "PASS"
+			if !res.Passed {
+				status = "FAIL"
+			}
+			title = fmt.Sprintf("run test (last: %s in %s)", status, res.Duration)
+		}
+		lens.Command = &protocol.Command{
+			Title:     title,
+			Command:   "gnols.test",
+			Arguments: []interface{}{data.Path, data.Name},
+		}
+
+	case lensReferences:
+		if data.Position == nil {
+			return lens, errors.New("missing code lens position")
+		}
+		spans, err := h.references(ctx, data.Path, *data.Position)
+		if err != nil {
+			// the lens is still displayed, so it's resolved again later
+			slog.Error("code_lens references", "path", data.Path, "err", err)
+			lens.Command = &protocol.Command{Title: "references unavailable"}
+			return lens, nil
+		}
+		// the declaration is part of the references
+		n := max(len(spans)-1, 0)
+		title := fmt.Sprintf("%d references", n)
+		if n == 1 {
+			title = "1 reference"
+		}
+		lens.Command = &protocol.Command{
+			// gnols.references is handled by the client, which is expected to
+			// display the references of the function.
+			Title:     title,
+			Command:   "gnols.references",
+			Arguments: []interface{}{data.Path, data.Position},
+		}
+
+	default:
+		return lens, fmt.Errorf("unknown code lens kind %q", data.Kind)
+	}
+	return lens, nil
+}
+
+// references returns the references of the identifier at pos in the file
+// path, using gopls.
+func (h *handler) references(ctx context.Context, path string, pos protocol.Position) ([]gno.Span, error) {
+	bm, err := h.getBinManager(path)
+	if err != nil {
+		return nil, err
+	}
+	return bm.References(ctx, uri.File(path), pos.Line, h.byteColumn(path, pos))
+}
+
+// byteColumn returns the column of pos in bytes, as expected by gopls, while
+// the LSP column counts UTF-16 units. The column is kept if the document
+// isn't open.
+func (h *handler) byteColumn(path string, pos protocol.Position) uint32 {
+	doc, ok := h.documents.Get(uri.File(path))
+	if !ok {
+		return pos.Character
+	}
+	lineStart := doc.PositionToOffset(protocol.Position{Line: pos.Line})
+	return uint32(doc.PositionToOffset(pos) - lineStart)
+}
+
+// codeLensRefreshDelay is the delay without change after which the client is
+// asked to refresh the code lenses, so they aren't refreshed on each keystroke.
+const codeLensRefreshDelay = 500 * time.Millisecond
+
+// refreshCodeLens asks the client to request the code lenses again, so they
+// follow the document changes even if it isn't saved. The request is sent once
+// the changes stop for codeLensRefreshDelay.
+func (h *handler) refreshCodeLens() {
+	if !h.codeLensRefresh {
+		return
+	}
+	h.codeLensMu.Lock()
+	defer h.codeLensMu.Unlock()
+	if h.codeLensTimer != nil {
+		h.codeLensTimer.Stop()
+	}
+	h.codeLensTimer = time.AfterFunc(codeLensRefreshDelay, func() {
+		_, err := h.connPool.Call(context.Background(), protocol.MethodCodeLensRefresh, nil, nil)
+		if err != nil {
+			slog.Error("code lens refresh", "err", err)
+		}
+	})
+}
+
+// addTestCmds returns the code lenses running the tests of path. The "debug
+// test" code lenses are added if debug is true.
+func addTestCmds(path string, tAndB testFns, debug bool) []protocol.CodeLens {
 	cmds := []protocol.CodeLens{}
 	if len(tAndB.Tests) == 0 {
 		return cmds
@@ -78,11 +240,25 @@ func addTestCmds(path string, tAndB testFns) []protocol.CodeLens {
 	inFile := []string{}
 	for _, fn := range tAndB.Tests {
 		inFile = append(inFile, fn.Name)
+		cmds = append(cmds, protocol.CodeLens{
+			// resolved later to include the last result of the test
+			Range: fn.Rng,
+			Data: codeLensData{
+				Kind: lensTest,
+				Path: path,
+				Name: fn.Name,
+			},
+		})
+		if !debug {
+			continue
+		}
 		cmds = append(cmds, protocol.CodeLens{
 			Range: fn.Rng,
 			Command: &protocol.Command{
-				Title:     "run test",
-				Command:   "gnols.test",
+				// gnols.debugTest is handled by the client, which is expected to
+				// start a `gnols dap` session.
+				Title:     "debug test",
+				Command:   "gnols.debugTest",
 				Arguments: []interface{}{path, fn.Name},
 			},
 		})
@@ -97,6 +273,30 @@ func addTestCmds(path string, tAndB testFns) []protocol.CodeLens {
 	return cmds
 }
 
+// addReferencesCmds returns the code lenses showing the number of references
+// of the exported functions of doc. The references are counted when the code
+// lenses are resolved.
+func addReferencesCmds(doc *store.Document) []protocol.CodeLens {
+	cmds := []protocol.CodeLens{}
+	for _, d := range doc.Pgf.File.Decls {
+		fn, ok := d.(*ast.FuncDecl)
+		if !ok || !fn.Name.IsExported() {
+			continue
+		}
+		rng := doc.RangeOf(fn.Name.Pos(), fn.Name.End())
+		cmds = append(cmds, protocol.CodeLens{
+			Range: rng,
+			Data: codeLensData{
+				Kind:     lensReferences,
+				Path:     doc.Path,
+				Name:     fn.Name.Name,
+				Position: &rng.Start,
+			},
+		})
+	}
+	return cmds
+}
+
 func addBenchCmds(path string, tAndB testFns) []protocol.CodeLens {
 	cmds := []protocol.CodeLens{}
 	if len(tAndB.Benchmarks) == 0 {
@@ -140,15 +340,15 @@ func testsAndBenchmarks(doc *store.Document) testFns {
 			continue
 		}
 
+		// The lens is placed on the function name
+		rng := doc.RangeOf(fn.Name.Pos(), fn.Name.End())
+
 		if matchTestFunc(fn, testRe, "T") {
-			slog.Info("code_lens", "match", fn.Name.Name)
-			rng := doc.SpanToRange(int(fn.Pos()), int(fn.End()))
-			slog.Info("code_lens", "rng", rng)
+			slog.Info("code_lens", "match", fn.Name.Name, "rng", rng)
 			out.Tests = append(out.Tests, testFn{fn.Name.Name, rng})
 		}
 
 		if matchTestFunc(fn, benchmarkRe, "B") {
-			rng := doc.SpanToRange(int(fn.Pos()), int(fn.End()))
 			out.Benchmarks = append(out.Benchmarks, testFn{fn.Name.Name, rng})
 		}
 	}
@@ -156,6 +356,21 @@ func testsAndBenchmarks(doc *store.Document) testFns {
 	return out
 }
 
+// mainFunc returns the `func main()` declaration of doc, or nil if doc isn't
+// part of a main package.
+func mainFunc(doc *store.Document) *ast.FuncDecl {
+	if doc.Pgf.File.Name.Name != "main" {
+		return nil
+	}
+	for _, d := range doc.Pgf.File.Decls {
+		fn, ok := d.(*ast.FuncDecl)
+		if ok && fn.Recv == nil && fn.Name.Name == "main" {
+			return fn
+		}
+	}
+	return nil
+}
+
 func matchTestFunc(fn *ast.FuncDecl, nameRe *regexp.Regexp, paramID string) bool {
 	if !nameRe.MatchString(fn.Name.Name) {
 		return false
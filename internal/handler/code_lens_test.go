@@ -1,10 +1,20 @@
 package handler
 
 import (
+	"context"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+
+	"github.com/jdkato/gnols/internal/gno"
 	"github.com/jdkato/gnols/internal/store"
 )
 
@@ -37,3 +47,258 @@ func TestCodeLensFind(t *testing.T) {
 		t.Errorf("expected = %v, got = %v", 2, len(found.Benchmarks))
 	}
 }
+
+func TestCodeLensRange(t *testing.T) {
+	content := `package foo
+
+// Tést with nön-ASCII çharacters 😀
+func TestA(t *testing.T) {}
+
+/* 😀 */ func BenchmarkB(b *testing.B) {}
+`
+	doc := &store.Document{
+		Path:  "foo_test.gno",
+		Lines: strings.SplitAfter(content, "\n"),
+		Pgf:   store.NewParsedGnoFile("foo_test.gno", content),
+	}
+
+	found := testsAndBenchmarks(doc)
+
+	if len(found.Tests) != 1 || len(found.Benchmarks) != 1 {
+		t.Fatalf("expected 1 test and 1 benchmark, got %v", found)
+	}
+	expected := protocol.Range{
+		Start: protocol.Position{Line: 3, Character: 5},
+		End:   protocol.Position{Line: 3, Character: 10},
+	}
+	if found.Tests[0].Rng != expected {
+		t.Errorf("expected = %v, got = %v", expected, found.Tests[0].Rng)
+	}
+	// 😀 is 2 UTF-16 code units
+	expected = protocol.Range{
+		Start: protocol.Position{Line: 5, Character: 14},
+		End:   protocol.Position{Line: 5, Character: 24},
+	}
+	if found.Benchmarks[0].Rng != expected {
+		t.Errorf("expected = %v, got = %v", expected, found.Benchmarks[0].Rng)
+	}
+}
+
+func TestCodeLensMain(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected bool
+	}{
+		{
+			name:     "main package",
+			content:  "package main\n\nfunc main() {}\n",
+			expected: true,
+		},
+		{
+			name:     "main package without main func",
+			content:  "package main\n\nfunc foo() {}\n",
+			expected: false,
+		},
+		{
+			name:     "main method",
+			content:  "package main\n\ntype T struct{}\n\nfunc (T) main() {}\n",
+			expected: false,
+		},
+		{
+			name:     "other package",
+			content:  "package foo\n\nfunc main() {}\n",
+			expected: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := &store.Document{
+				Path: "main.gno",
+				Pgf:  store.NewParsedGnoFile("main.gno", tt.content),
+			}
+
+			fn := mainFunc(doc)
+
+			if found := fn != nil; found != tt.expected {
+				t.Errorf("expected = %v, got = %v", tt.expected, found)
+			}
+		})
+	}
+}
+
+func TestResolveCodeLensTest(t *testing.T) {
+	h := &handler{
+		testResults: map[string]gno.TestResult{
+			testKey("/pkg", "TestA"): {Name: "TestA", Passed: false, Duration: 1500 * time.Millisecond},
+		},
+	}
+	tests := []struct {
+		name          string
+		test          string
+		expectedTitle string
+	}{
+		{
+			name:          "never run",
+			test:          "TestB",
+			expectedTitle: "run test",
+		},
+		{
+			name:          "last result",
+			test:          "TestA",
+			expectedTitle: "run test (last: FAIL in 1.5s)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lens, err := h.resolveCodeLens(context.Background(), protocol.CodeLens{
+				Data: codeLensData{Kind: lensTest, Path: "/pkg/x_test.gno", Name: tt.test},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if lens.Command == nil || lens.Command.Title != tt.expectedTitle {
+				t.Errorf("expected = %v, got = %v", tt.expectedTitle, lens.Command)
+			}
+		})
+	}
+}
+
+// callCounter is a connection counting the requests sent to the client.
+type callCounter struct {
+	jsonrpc2.Conn
+	calls atomic.Int32
+}
+
+func (c *callCounter) Call(context.Context, string, interface{}, interface{}) (jsonrpc2.ID, error) {
+	c.calls.Add(1)
+	return jsonrpc2.ID{}, nil
+}
+
+func TestRefreshCodeLensDebounce(t *testing.T) {
+	conn := &callCounter{}
+	h := &handler{connPool: conn, codeLensRefresh: true}
+
+	// like a few keystrokes
+	for i := 0; i < 5; i++ {
+		h.refreshCodeLens()
+	}
+	time.Sleep(2 * codeLensRefreshDelay)
+
+	if n := conn.calls.Load(); n != 1 {
+		t.Errorf("expected = %v, got = %v", 1, n)
+	}
+}
+
+func TestCodeLensReferencesColumn(t *testing.T) {
+	h := &handler{documents: store.NewDocumentStore()}
+	path := "/src/foo/foo.gno"
+	_, err := h.documents.Save(uri.File(path), "package foo\n\n/* 😀 é */ func Foo() {}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 😀 is 2 UTF-16 code units and 4 bytes, é is 1 UTF-16 code unit and 2 bytes
+	if col := h.byteColumn(path, protocol.Position{Line: 2, Character: 15}); col != 18 {
+		t.Errorf("expected = %v, got = %v", 18, col)
+	}
+	// the document isn't open
+	if col := h.byteColumn("/src/foo/bar.gno", protocol.Position{Line: 2, Character: 15}); col != 15 {
+		t.Errorf("expected = %v, got = %v", 15, col)
+	}
+}
+
+func TestCodeLensReferencesUnavailable(t *testing.T) {
+	h := &handler{
+		configLoaded: make(chan struct{}),
+		documents:    store.NewDocumentStore(),
+	}
+	close(h.configLoaded)
+	lens := protocol.CodeLens{
+		Data: codeLensData{
+			Kind:     lensReferences,
+			Path:     "/src/foo/foo.gno",
+			Name:     "Foo",
+			Position: &protocol.Position{Line: 2, Character: 5},
+		},
+	}
+
+	// there's no workspace, so gopls can't be used
+	lens, err := h.resolveCodeLens(context.Background(), lens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lens.Command == nil || lens.Command.Title != "references unavailable" {
+		t.Errorf("expected the references to be unavailable, got %v", lens.Command)
+	}
+}
+
+func TestCodeLensDebugTest(t *testing.T) {
+	tAndB := testFns{Tests: []testFn{{Name: "TestA"}}}
+	tests := []struct {
+		name     string
+		debug    bool
+		expected int
+	}{
+		{
+			name:     "client command",
+			debug:    true,
+			expected: 1,
+		},
+		{
+			name:     "unsupported",
+			debug:    false,
+			expected: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := 0
+			for _, lens := range addTestCmds("/pkg/x_test.gno", tAndB, tt.debug) {
+				if lens.Command != nil && lens.Command.Command == "gnols.debugTest" {
+					n++
+				}
+			}
+
+			if n != tt.expected {
+				t.Errorf("expected = %v, got = %v", tt.expected, n)
+			}
+		})
+	}
+}
+
+func TestClientCommands(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     interface{}
+		expected map[string]bool
+	}{
+		{
+			name:     "no options",
+			opts:     nil,
+			expected: map[string]bool{},
+		},
+		{
+			name:     "no client commands",
+			opts:     map[string]interface{}{"other": true},
+			expected: map[string]bool{},
+		},
+		{
+			name: "client commands",
+			opts: map[string]interface{}{
+				"clientCommands": []interface{}{"gnols.references", 1, "gnols.debugTest"},
+			},
+			expected: map[string]bool{"gnols.references": true, "gnols.debugTest": true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmds := clientCommands(tt.opts)
+
+			if !reflect.DeepEqual(cmds, tt.expected) {
+				t.Errorf("expected = %v, got = %v", tt.expected, cmds)
+			}
+		})
+	}
+}
@@ -13,7 +13,11 @@ func (h *handler) handleTextDocumentReferences(ctx context.Context, reply jsonrp
 		return replyErr(ctx, reply, err)
 	}
 
-	spans, err := h.getBinManager().References(ctx,
+	bm, err := h.getBinManager(params.TextDocument.URI.Filename())
+	if err != nil {
+		return replyErr(ctx, reply, err)
+	}
+	spans, err := bm.References(ctx,
 		params.TextDocument.URI, params.Position.Line, params.Position.Character,
 	)
 	if err != nil {
@@ -32,7 +36,11 @@ func (h *handler) handleTextDocumentDefinition(ctx context.Context, reply jsonrp
 		return replyErr(ctx, reply, err)
 	}
 
-	def, err := h.getBinManager().Definition(ctx,
+	bm, err := h.getBinManager(params.TextDocument.URI.Filename())
+	if err != nil {
+		return replyErr(ctx, reply, err)
+	}
+	def, err := bm.Definition(ctx,
 		params.TextDocument.URI, params.Position.Line, params.Position.Character,
 	)
 	if err != nil {
@@ -48,7 +56,11 @@ func (h *handler) handleTextDocumentImplementation(ctx context.Context, reply js
 		return replyErr(ctx, reply, err)
 	}
 
-	spans, err := h.getBinManager().Implementation(ctx,
+	bm, err := h.getBinManager(params.TextDocument.URI.Filename())
+	if err != nil {
+		return replyErr(ctx, reply, err)
+	}
+	spans, err := bm.Implementation(ctx,
 		params.TextDocument.URI, params.Position.Line, params.Position.Character,
 	)
 	if err != nil {
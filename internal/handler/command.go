@@ -1,12 +1,17 @@
 package handler
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"log/slog"
 	"path/filepath"
+	"sync"
 
 	"go.lsp.dev/jsonrpc2"
 	"go.lsp.dev/protocol"
+
+	"github.com/jdkato/gnols/internal/gno"
 )
 
 func (h *handler) handleExecuteCommand(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
@@ -16,27 +21,136 @@ func (h *handler) handleExecuteCommand(ctx context.Context, reply jsonrpc2.Repli
 	}
 	slog.Info("execute_command", "command", params.Command, "args", params.Arguments)
 
-	switch params.Command { //nolint:gocritic
+	switch params.Command {
 	case "gnols.test":
-		file, ok := params.Arguments[0].(string)
-		if !ok {
-			return &jsonrpc2.Error{Code: jsonrpc2.InvalidParams}
+		args, err := stringArgs(params.Arguments, 2)
+		if err != nil {
+			return replyErr(ctx, reply, err)
 		}
-		pkg := filepath.Dir(file)
+		h.runTest(filepath.Dir(args[0]), args[1])
 
-		test, ok := params.Arguments[1].(string)
-		if !ok {
-			return &jsonrpc2.Error{Code: jsonrpc2.InvalidParams}
+	case "gnols.run":
+		args, err := stringArgs(params.Arguments, 1)
+		if err != nil {
+			return replyErr(ctx, reply, err)
 		}
+		go h.runFile(context.Background(), args[0])
 
-		h.runTest(pkg, test)
+	case "gnols.coverage":
+		args, err := stringArgs(params.Arguments, 1)
+		if err != nil {
+			return replyErr(ctx, reply, err)
+		}
+		go h.runCoverage(context.Background(), filepath.Dir(args[0]))
 	}
 
 	return reply(ctx, nil, nil)
 }
 
+// stringArgs returns the n first arguments of a command, which must be
+// strings.
+func stringArgs(args []interface{}, n int) ([]string, error) {
+	if len(args) < n {
+		return nil, jsonrpc2.NewError(jsonrpc2.InvalidParams, fmt.Sprintf("expected %d arguments, got %d", n, len(args)))
+	}
+	strs := make([]string, n)
+	for i := range strs {
+		s, ok := args[i].(string)
+		if !ok {
+			return nil, jsonrpc2.NewError(jsonrpc2.InvalidParams, fmt.Sprintf("argument %d must be a string", i+1))
+		}
+		strs[i] = s
+	}
+	return strs, nil
+}
+
 func (h *handler) runTest(pkg, test string) {
 	slog.Info("execute_command", "pkg", pkg, "test", test)
-	out, _ := h.getBinManager().RunTest(pkg, test)
+	bm, err := h.getBinManager(pkg)
+	if err != nil {
+		slog.Error("execute_command", "err", err)
+		return
+	}
+	out, _ := bm.RunTest(pkg, test)
 	slog.Info("execute_command", "out", string(out))
+	// keep results for the code lenses
+	h.testResultsMu.Lock()
+	for _, res := range gno.ParseTestResults(string(out)) {
+		h.testResults[testKey(pkg, res.Name)] = res
+	}
+	h.testResultsMu.Unlock()
+	h.refreshCodeLens()
+}
+
+// testKey returns the key of the test name of pkg in handler.testResults.
+func testKey(pkg, name string) string {
+	return pkg + "#" + name
+}
+
+// runFile executes file and streams its output to the client as log messages.
+func (h *handler) runFile(ctx context.Context, file string) {
+	slog.Info("execute_command", "run", file)
+	var (
+		stdout = &logWriter{ctx: ctx, h: h, typ: protocol.MessageTypeLog}
+		stderr = &logWriter{ctx: ctx, h: h, typ: protocol.MessageTypeError}
+	)
+	bm, err := h.getBinManager(file)
+	if err != nil {
+		h.notifyErr(ctx, err)
+		return
+	}
+	err = bm.Run(ctx, file, stdout, stderr)
+	stdout.Flush()
+	stderr.Flush()
+	if err != nil {
+		h.notifyErr(ctx, fmt.Errorf("gno run %s: %w", file, err))
+		return
+	}
+	h.notify(ctx, protocol.MethodWindowLogMessage, &protocol.LogMessageParams{
+		Message: fmt.Sprintf("gno run %s: done", file),
+		Type:    protocol.MessageTypeInfo,
+	})
+}
+
+// logWriter is an io.Writer that sends each written line to the client as a
+// `window/logMessage` notification.
+type logWriter struct {
+	ctx context.Context
+	h   *handler
+	typ protocol.MessageType
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.send(string(w.buf[:i]))
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush sends the remaining incomplete line, if any.
+func (w *logWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) > 0 {
+		w.send(string(w.buf))
+		w.buf = nil
+	}
+}
+
+func (w *logWriter) send(line string) {
+	w.h.notify(w.ctx, protocol.MethodWindowLogMessage, &protocol.LogMessageParams{
+		Message: line,
+		Type:    w.typ,
+	})
 }
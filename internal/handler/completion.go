@@ -2,17 +2,27 @@ package handler
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"go/ast"
-	gotoken "go/token"
+	"go/token"
+	"go/types"
 	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
-	"github.com/davecgh/go-spew/spew"
-	"github.com/jdkato/gnols/internal/gno"
-	"github.com/jdkato/gnols/internal/stdlib"
 	"go.lsp.dev/jsonrpc2"
 	"go.lsp.dev/protocol"
 	"golang.org/x/tools/go/ast/astutil"
+
+	"github.com/jdkato/gnols/internal/gno"
+	"github.com/jdkato/gnols/internal/store"
 )
 
 func (h *handler) handleTextDocumentCompletion(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
@@ -25,276 +35,890 @@ func (h *handler) handleTextDocumentCompletion(ctx context.Context, reply jsonrp
 	if !ok {
 		return replyNoDocFound(ctx, reply, params.TextDocument.URI)
 	}
+	if int(params.Position.Line) >= len(doc.Lines) {
+		return replyErr(ctx, reply, errors.New("line out of range"))
+	}
 
-	token, err := doc.TokenAt(params.Position)
+	if doc.Mod != nil {
+		return reply(ctx, h.modCompletionItems(doc, params.Position), nil)
+	}
+
+	items := []protocol.CompletionItem{}
+	h.completed = make(map[string]completedSymbol)
+	c, err := h.newCursor(doc, doc.PositionToOffset(params.Position))
 	if err != nil {
-		return replyErr(ctx, reply, err)
+		slog.Error("completion", "err", err)
+		return reply(ctx, items, nil)
 	}
-	text := strings.TrimSpace(token.Text)
-	// Extract symbol name and prefix from text
-	// TODO ensure textDocument/completion is only triggered after a dot '.' and
-	// if yes why ? Is it a client limitation or configuration, or a LSP spec?
-	selectors := strings.FieldsFunc(text, func(r rune) bool { return r == '.' })
+	items = append(items, c.complete()...)
+	return reply(ctx, items, nil)
+}
 
-	slog.Info("completion", "text", text, "selectors", selectors)
+// completionTriggers returns the completion trigger characters: the dot for
+// selectors, the quote and slash for import paths, and the identifier
+// characters so identifiers are completed while they're typed.
+func completionTriggers() []string {
+	triggers := []string{".", "\"", "/", "_"}
+	for r := 'a'; r <= 'z'; r++ {
+		triggers = append(triggers, string(r), strings.ToUpper(string(r)))
+	}
+	return triggers
+}
 
-	items := []protocol.CompletionItem{}
+// symbolItem returns the completion item of sym, identified by id. The
+// signature and the documentation are added when the item is resolved.
+func symbolItem(id string, sym gno.Symbol) protocol.CompletionItem {
+	return protocol.CompletionItem{
+		Label:      sym.Name,
+		InsertText: sym.Name,
+		Kind:       symbolToKind(sym.Kind),
+		Data:       completionData{ID: id},
+	}
+}
 
-	pos := gotoken.Pos(doc.PositionToOffset(params.Position))
-	nodes, _ := astutil.PathEnclosingInterval(doc.Pgf.File, pos, pos)
-	spew.Dump("ENCLOSING NODES", nodes)
+// indexSymbolItems returns the items of syms, symbols of the indexed package
+// pkg.
+func (c *cursor) indexSymbolItems(pkg *gno.Package, syms []gno.Symbol) []protocol.CompletionItem {
+	var (
+		items     = make([]protocol.CompletionItem, 0, len(syms))
+		link, own = c.h.pkgLink(pkg.ImportPath)
+	)
+	for _, sym := range syms {
+		id := pkg.SymbolID(sym)
+		if own {
+			// workspace symbols aren't in the stdlib index
+			c.remember(id, sym, symbolLink(link, sym))
+		}
+		items = append(items, symbolItem(id, sym))
+	}
+	return items
+}
 
-	for _, n := range nodes {
-		var syms []gno.Symbol
-		switch n := n.(type) {
-		case *ast.Ident:
-			// related test-cases:
-			// - cmd/gnols/testdata/document_completion_local_struct_novar.txtar
-
-			// TODO check if it works when multiple selectors
-			syms = symbolFinder{h.currentPkg.Symbols}.find([]string{n.Name})
-
-		case *ast.BlockStmt:
-			// related test-cases:
-			// - cmd/gnols/testdata/document_completion_local_struct.txtar
-			// - cmd/gnols/testdata/document_completion_local_struct_one_selector.txtar
-			// - cmd/gnols/testdata/document_completion_local_struct_two_selectors.txtar
-			// - cmd/gnols/testdata/document_completion_local_struct_three_selectors_inline.txtar
-			// - cmd/gnols/testdata/document_completion_local_struct_three_selectors.txtar
-
-			// Check if selectors[0] has been assigned here
-			for _, t := range n.List {
-				switch t := t.(type) {
-				case *ast.AssignStmt:
-					if nodeName(t.Lhs[0]) == selectors[0] && t.Tok == gotoken.DEFINE {
-						// found selectors[0] assignment, now fetch type
-						if cl, ok := t.Rhs[0].(*ast.CompositeLit); ok {
-							typ := nodeName(cl.Type)
-							// FIXME typ can come from an other package
-							syms = symbolFinder{h.currentPkg.Symbols}.find(append([]string{typ}, selectors[1:]...))
-							break
-						}
-					}
-				}
+// cursor is a position in a document, along with the type information of the
+// document's package. It's used to compute the completion items and the code
+// actions at that position.
+type cursor struct {
+	h *handler
+	// ws is the workspace folder of doc.
+	ws   *workspace
+	chk  *gno.Checker
+	pkg  *gno.CheckedPackage
+	file *ast.File
+	doc  *store.Document
+	// offset and pos are the position of the cursor in file.
+	offset int
+	pos    token.Pos
+}
+
+func (h *handler) newCursor(doc *store.Document, offset int) (*cursor, error) {
+	if doc.Mod != nil {
+		return nil, fmt.Errorf("%s isn't a Gno file", doc.Path)
+	}
+	chk := h.checker(filepath.Dir(doc.Path), map[string]string{doc.Path: doc.Content})
+	pkg, err := chk.Check(h.importPathOf(filepath.Dir(doc.Path)), doc.Path)
+	if err != nil {
+		return nil, err
+	}
+	file := chk.File(doc.Path)
+	tf := chk.Fset.File(file.Pos())
+	if offset > tf.Size() {
+		offset = tf.Size()
+	}
+	return &cursor{
+		h:      h,
+		ws:     h.workspaceOf(doc.Path),
+		chk:    chk,
+		pkg:    pkg,
+		file:   file,
+		doc:    doc,
+		offset: offset,
+		pos:    tf.Pos(offset),
+	}, nil
+}
+
+// importResolver returns the resolver of the imports of the package in dir,
+// which applies the replace and require directives of its gno.mod file.
+func (h *handler) importResolver(dir string) gno.Resolver {
+	mod, modDir, ok := h.findModFile(dir)
+	return func(path string) (string, bool) {
+		if !ok {
+			return h.resolveImport(path)
+		}
+		replaced, newPath := gno.ResolveModImport(mod, modDir, path)
+		if replaced != "" {
+			if fi, err := os.Stat(replaced); err == nil && fi.IsDir() {
+				return replaced, true
 			}
+			return "", false
+		}
+		if dir, ok := h.resolveImport(newPath); ok {
+			return dir, true
+		}
+		if gno.Requires(mod, newPath) {
+			// the module may have been downloaded with `gno mod download`
+			dir := filepath.Join(gno.ModCacheDir(), filepath.FromSlash(newPath))
+			if fi, err := os.Stat(dir); err == nil && fi.IsDir() {
+				return dir, true
+			}
+		}
+		return "", false
+	}
+}
 
-		case *ast.SelectorExpr:
-			switch x := n.X.(type) {
-			case *ast.Ident:
-				// related test-cases:
-				// - cmd/gnols/testdata/document_completion_subpackage.txtar
-				// - cmd/gnols/testdata/document_completion_examples_ufmt.txtar
-				// - cmd/gnols/testdata/document_completion_stdlib_bufio.txtar
-				// - cmd/gnols/testdata/document_completion_subpackage_multiple_selectors.txtar
-				// - cmd/gnols/testdata/document_completion_stdlib_strconv.txtar
-
-				// look up in subpackages (TODO also add imported packages)
-				pkg := x.Name
-				// look up pkg in subpkgs
-				for _, sub := range h.subPkgs {
-					if sub.Name == pkg {
-						syms = symbolFinder{sub.Symbols}.find(selectors[1:])
-						break
-					}
-				}
-				if len(syms) == 0 {
-					// look up in stdlib
-					for _, stdPkg := range stdlib.Packages {
-						if stdPkg.Name == pkg {
-							syms = symbolFinder{stdPkg.Symbols}.find(selectors[1:])
-							break
-						}
-					}
-				}
+// resolveImport returns the directory of the package imported with path,
+// looking in the workspace, and then in the gno repository if configured.
+func (h *handler) resolveImport(path string) (string, bool) {
+	for _, pkg := range h.workspacePkgs() {
+		if pkg.ImportPath == path {
+			return pkg.Dir, true
+		}
+	}
+	if h.settings == nil || h.settings.root == "" {
+		return "", false
+	}
+	for _, dir := range []string{"gnovm/stdlibs", "examples"} {
+		dir = filepath.Join(h.settings.root, dir, filepath.FromSlash(path))
+		if fi, err := os.Stat(dir); err == nil && fi.IsDir() {
+			return dir, true
+		}
+	}
+	return "", false
+}
 
-			case *ast.CallExpr:
-				// related test-cases:
-				// - cmd/gnols/testdata/document_completion_func_ret.txtar
+// importPathOf returns the import path of the workspace package in dir.
+func (h *handler) importPathOf(dir string) string {
+	return gno.ImportPath(h.workspaceOf(dir).folder, dir)
+}
 
-				// this a call, find return type
-				// TODO try to replace selectors with the func name without the ()
-				// so we dont need to remove them in symbolFinder
-				// See if it works with multiple selectors.
-				syms = symbolFinder{h.currentPkg.Symbols}.find(selectors)
-			}
+// lookupIndexedPkg returns the package of the symbol indexes whose import path
+// is path.
+func (h *handler) lookupIndexedPkg(path string) *gno.Package {
+	for _, pkg := range h.workspacePkgs() {
+		if pkg.ImportPath == path {
+			return &pkg
+		}
+	}
+	for _, pkg := range h.stdlibPkgs() {
+		if pkg.ImportPath == path {
+			return &pkg
+		}
+	}
+	return nil
+}
 
-		case *ast.FuncDecl:
-			for _, param := range n.Type.Params.List {
-				if param.Names[0].Name == selectors[0] {
-					// match, find corresponding type
-					switch t := param.Type.(type) {
-					case *ast.Ident:
-						// related test-cases:
-						// - cmd/gnols/testdata/document_completion_func_args.txtar
-						// - cmd/gnols/testdata/document_completion_func_args3c.txtar
-						// - cmd/gnols/testdata/document_completion_func_args3b.txtar
-						// - cmd/gnols/testdata/document_completion_func_args6.txtar
-
-						typ := t.Name
-						syms = symbolFinder{h.currentPkg.Symbols}.find(append([]string{typ}, selectors[1:]...))
-
-					case *ast.SelectorExpr:
-						// related test-cases:
-						// - cmd/gnols/testdata/document_completion_func_args2.txtar
-						// - cmd/gnols/testdata/document_completion_func_args4.txtar
-						// - cmd/gnols/testdata/document_completion_func_args5.txtar
-
-						pkg := t.X.(*ast.Ident).Name
-						typ := t.Sel.Name
-						// look up pkg in subpkgs
-						for _, sub := range h.subPkgs {
-							if sub.Name == pkg {
-								syms = symbolFinder{sub.Symbols}.find(append([]string{typ}, selectors[1:]...))
-								break
-							}
-						}
-						if len(syms) == 0 {
-							// look up in stdlib
-							for _, stdPkg := range stdlib.Packages {
-								if stdPkg.Name == pkg {
-									syms = symbolFinder{stdPkg.Symbols}.find(append([]string{typ}, selectors[1:]...))
-									break
-								}
-							}
-						}
-
-					case *ast.InterfaceType:
-						// related test-cases:
-						// - cmd/gnols/testdata/document_completion_func_args3.txtar
-
-						// TODO address when len(selectors)>1
-						for _, method := range t.Methods.List {
-							syms = append(syms, gno.Symbol{
-								Name:      method.Names[0].Name,
-								Kind:      "method",
-								Doc:       method.Comment.Text(),
-								Signature: doc.Content[method.Pos()-1 : method.End()-1],
-							})
-						}
-
-					default:
-						panic("FIXME cannot find type")
-					}
-				}
+// lookupIndexedPkgsByName returns the packages of the symbol indexes named
+// name, the workspace's sub packages first.
+func (h *handler) lookupIndexedPkgsByName(name string) []*gno.Package {
+	return append(lookupPkgs(h.workspacePkgs(), name), lookupPkgs(h.stdlibPkgs(), name)...)
+}
+
+// importedPath returns the import path of the package named name in the
+// file, taking the import aliases into account.
+func (c *cursor) importedPath(name string) (string, bool) {
+	for _, spec := range c.file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		var local string
+		switch {
+		case spec.Name != nil:
+			local = spec.Name.Name
+		case c.h.lookupIndexedPkg(path) != nil:
+			local = c.h.lookupIndexedPkg(path).Name
+		case c.pkg.Info.PkgNameOf(spec) != nil:
+			local = c.pkg.Info.PkgNameOf(spec).Name()
+		default:
+			local = path[strings.LastIndex(path, "/")+1:]
+		}
+		if local == name {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// pkgsNamed returns the indexed packages which name refers to: the package
+// imported as name if any, otherwise all the packages named name, which can
+// be imported.
+func (c *cursor) pkgsNamed(name string) []*gno.Package {
+	if path, ok := c.importedPath(name); ok {
+		if pkg := c.h.lookupIndexedPkg(path); pkg != nil {
+			return []*gno.Package{pkg}
+		}
+		return nil
+	}
+	return c.h.lookupIndexedPkgsByName(name)
+}
+
+// pkgOf returns the indexed package id refers to, resolved through the
+// imports of the file. If id isn't imported, the first package named id is
+// returned.
+func (c *cursor) pkgOf(id *ast.Ident) *gno.Package {
+	if pn, ok := c.pkg.Info.Uses[id].(*types.PkgName); ok {
+		return c.h.lookupIndexedPkg(pn.Imported().Path())
+	}
+	if pkgs := c.pkgsNamed(id.Name); len(pkgs) > 0 {
+		return pkgs[0]
+	}
+	return nil
+}
+
+func (c *cursor) complete() []protocol.CompletionItem {
+	if lit := c.importPathAt(); lit != nil {
+		return c.importPathItems(lit)
+	}
+	if lit, prefix, ok := c.structLitKeyAt(); ok {
+		if items := c.structLitItems(lit, prefix); items != nil {
+			return items
+		}
+	}
+	sel, prefix := c.selectorAt()
+	if sel == nil {
+		selectors := c.textSelectors()
+		if len(selectors) > 1 {
+			// the cursor isn't part of the AST, which happens when the
+			// parser skips a broken declaration.
+			return c.findInIndex(selectors[0], selectors[1:])
+		}
+		if ts := c.typeCaseAt(); ts != nil {
+			return c.typeCaseItems(ts, selectors[0])
+		}
+		return c.identItems(selectors[0])
+	}
+	slog.Info("completion", "selector", sel.Sel.Name, "prefix", prefix)
+
+	if id, ok := sel.X.(*ast.Ident); ok {
+		if pn, ok := c.pkg.Info.Uses[id].(*types.PkgName); ok && c.chk.Loaded(pn.Imported().Path()) {
+			return c.objectItems(c.pkgMembers(pn.Imported()), "", prefix)
+		}
+	}
+	if tv, ok := c.pkg.Info.Types[sel.X]; ok && tv.Type != nil && tv.Type != types.Typ[types.Invalid] {
+		var owner string
+		if named, ok := deref(tv.Type).(*types.Named); ok {
+			owner = named.Obj().Name()
+		}
+		items := c.objectItems(c.members(tv.Type, tv.IsType()), owner, prefix)
+		if !tv.IsType() {
+			items = append(items, c.postfixItems(sel, prefix, tv.Type)...)
+		}
+		return items
+	}
+	return c.indexItems(sel, prefix)
+}
+
+// importPathAt returns the path of the import spec containing the cursor, if
+// any.
+func (c *cursor) importPathAt() *ast.BasicLit {
+	for _, spec := range c.file.Imports {
+		lit := spec.Path
+		if lit == nil || lit.Kind != token.STRING || lit.Pos() >= c.pos {
+			continue
+		}
+		if c.pos < lit.End() || (c.pos == lit.End() && !terminated(lit)) {
+			return lit
+		}
+	}
+	return nil
+}
+
+// terminated returns true if the string literal lit has its closing quote.
+func terminated(lit *ast.BasicLit) bool {
+	v := lit.Value
+	return len(v) >= 2 && v[len(v)-1] == v[0]
+}
+
+// importPathItems returns the items of the known packages whose import path
+// matches the content of lit typed before the cursor: workspace packages,
+// indexed packages and modules required in gno.mod.
+func (c *cursor) importPathItems(lit *ast.BasicLit) []protocol.CompletionItem {
+	var (
+		tf    = c.chk.Fset.File(c.file.Pos())
+		start = tf.Offset(lit.Pos()) + 1
+		end   = tf.Offset(lit.End())
+	)
+	if terminated(lit) {
+		end--
+	}
+	var (
+		prefix = c.doc.Content[start:c.offset]
+		rng    = protocol.Range{
+			Start: c.doc.OffsetToPosition(start),
+			End:   c.doc.OffsetToPosition(end),
+		}
+		seen  = make(map[string]bool)
+		items = []protocol.CompletionItem{}
+	)
+	// skip the packages already imported
+	for _, spec := range c.file.Imports {
+		if p, err := strconv.Unquote(spec.Path.Value); err == nil && spec.Path != lit {
+			seen[p] = true
+		}
+	}
+	add := func(path, name, doc string, draft bool) {
+		if seen[path] || !strings.HasPrefix(path, prefix) {
+			return
+		}
+		seen[path] = true
+		c.rememberPkg(path, name, doc)
+		detail := "package " + name
+		if draft {
+			detail += " (draft)"
+		}
+		items = append(items, protocol.CompletionItem{
+			Label:  path,
+			Kind:   protocol.CompletionItemKindModule,
+			Detail: detail,
+			Data:   completionData{ID: path},
+			TextEdit: &protocol.TextEdit{
+				Range:   rng,
+				NewText: path,
+			},
+		})
+	}
+	for _, pkgs := range [][]gno.Package{c.h.workspacePkgs(), c.h.stdlibPkgs()} {
+		for _, pkg := range pkgs {
+			add(pkg.ImportPath, pkg.Name, pkg.Doc, pkg.Draft)
+		}
+	}
+	if mod, _, ok := c.h.findModFile(filepath.Dir(c.doc.Path)); ok {
+		for _, req := range mod.Require {
+			add(req.Mod.Path, path.Base(req.Mod.Path), "", false)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Label < items[j].Label })
+	return items
+}
+
+// identItems returns the items for the identifier being typed at the cursor:
+// the objects in scope, from the innermost scope to the universe, the
+// packages which can be imported, the snippets and the keywords. Items are
+// sorted by proximity, then by kind. In a return statement, the values
+// matching the result type come first, along with its zero value.
+func (c *cursor) identItems(prefix string) []protocol.CompletionItem {
+	items := []protocol.CompletionItem{}
+	if c.inCommentOrString() {
+		return items
+	}
+	scope := c.pkg.Types.Scope().Innermost(c.pos)
+	if scope == nil {
+		scope = c.pkg.Info.Scopes[c.file]
+	}
+	var (
+		seen     = make(map[string]bool)
+		expected = c.expectedType()
+		matching = make(map[string]bool)
+	)
+	for depth := 0; scope != nil; depth, scope = depth+1, scope.Parent() {
+		// objects of the local scopes must be declared before the cursor
+		pkgScope := c.pkg.Types.Scope()
+		local := scope != types.Universe && scope != pkgScope && scope.Parent() != pkgScope
+		for _, name := range scope.Names() {
+			obj := scope.Lookup(name)
+			if seen[name] || name == "_" || !strings.HasPrefix(name, prefix) {
+				continue
+			}
+			if local && obj.Pos() >= c.pos {
+				continue
+			}
+			seen[name] = true
+			item := c.objectItem(obj, "")
+			item.SortText = fmt.Sprintf("%02d%d%s", depth, kindRank(obj), name)
+			if expected != nil && matches(obj, expected) {
+				matching[name] = true
+			}
+			items = append(items, item)
+		}
+	}
+	// packages not imported yet
+	for _, pkgs := range [][]gno.Package{c.h.workspacePkgs(), c.h.stdlibPkgs()} {
+		for _, pkg := range pkgs {
+			if seen[pkg.Name] || !strings.HasPrefix(pkg.Name, prefix) || c.imports(pkg.ImportPath) {
+				continue
+			}
+			c.rememberPkg(pkg.ImportPath, pkg.Name, pkg.Doc)
+			items = append(items, protocol.CompletionItem{
+				Label:               pkg.Name,
+				InsertText:          pkg.Name,
+				Kind:                protocol.CompletionItemKindModule,
+				Detail:              fmt.Sprintf("import %q", pkg.ImportPath),
+				Data:                completionData{ID: pkg.ImportPath},
+				SortText:            "98" + pkg.Name,
+				AdditionalTextEdits: []protocol.TextEdit{c.importEdit(pkg.ImportPath)},
+			})
+		}
+	}
+	items = append(items, c.snippetItems(prefix)...)
+	for _, kw := range keywords {
+		if strings.HasPrefix(kw, prefix) {
+			items = append(items, protocol.CompletionItem{
+				Label:      kw,
+				InsertText: kw,
+				Kind:       protocol.CompletionItemKindKeyword,
+				SortText:   "99" + kw,
+			})
+		}
+	}
+	if expected != nil {
+		if zero := zeroValue(expected, types.RelativeTo(c.pkg.Types)); !seen[zero] && strings.HasPrefix(zero, prefix) {
+			items = append(items, protocol.CompletionItem{
+				Label:      zero,
+				InsertText: zero,
+				Kind:       protocol.CompletionItemKindValue,
+				Detail:     "zero value of " + types.TypeString(expected, types.RelativeTo(c.pkg.Types)),
+			})
+			matching[zero] = true
+		}
+		for i := range items {
+			if matching[items[i].Label] {
+				items[i].SortText = "0" + items[i].SortText
+			} else {
+				items[i].SortText = "1" + items[i].SortText
 			}
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].SortText < items[j].SortText })
+	return items
+}
 
-		case *ast.File:
-			// final node, check for global variable declaration that could match
-			// selectors[0]
-			for _, t := range n.Decls {
-				if len(syms) > 0 {
-					break
+// keywords are the Go keywords, which are also the Gno keywords.
+var keywords = []string{
+	"break", "case", "chan", "const", "continue", "default", "defer", "else",
+	"fallthrough", "for", "func", "go", "goto", "if", "import", "interface",
+	"map", "package", "range", "return", "select", "struct", "switch", "type",
+	"var",
+}
+
+// kindRank ranks the objects by kind: values first, then functions, types and
+// packages.
+func kindRank(obj types.Object) int {
+	switch obj.(type) {
+	case *types.Var, *types.Const, *types.Nil:
+		return 0
+	case *types.Func, *types.Builtin:
+		return 1
+	case *types.TypeName:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// inCommentOrString returns true if the cursor is inside a comment or a string
+// literal.
+func (c *cursor) inCommentOrString() bool {
+	for _, cg := range c.file.Comments {
+		if cg.Pos() < c.pos && c.pos <= cg.End() {
+			return true
+		}
+	}
+	path, _ := astutil.PathEnclosingInterval(c.file, c.pos, c.pos)
+	if len(path) > 0 {
+		if lit, ok := path[0].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+			return lit.Pos() < c.pos && c.pos < lit.End()
+		}
+	}
+	return false
+}
+
+// selectorAt returns the innermost selector expression whose selector is at
+// the cursor, and the selector name typed so far. The selector name is empty
+// if only the dot has been typed.
+func (c *cursor) selectorAt() (*ast.SelectorExpr, string) {
+	var (
+		found  *ast.SelectorExpr
+		prefix string
+	)
+	ast.Inspect(c.file, func(n ast.Node) bool {
+		if n == nil || n.Pos() > c.pos {
+			return false
+		}
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok || sel.X.End() >= c.pos {
+			return true
+		}
+		switch {
+		case sel.Sel.Pos() > c.pos:
+			// the parser has inserted a placeholder or has taken the next
+			// identifier as selector.
+			found, prefix = sel, ""
+		case c.pos <= sel.Sel.End():
+			found, prefix = sel, sel.Sel.Name
+		}
+		return true
+	})
+	return found, prefix
+}
+
+// pkgMembers returns the exported members of pkg, in declaration order.
+func (c *cursor) pkgMembers(pkg *types.Package) []types.Object {
+	var objs []types.Object
+	for _, name := range pkg.Scope().Names() {
+		if obj := pkg.Scope().Lookup(name); obj.Exported() {
+			objs = append(objs, obj)
+		}
+	}
+	sort.SliceStable(objs, func(i, j int) bool { return objs[i].Pos() < objs[j].Pos() })
+	return objs
+}
+
+// members returns the fields and methods of a value of type t: the fields
+// first, promoted fields after the direct ones, then the methods in
+// declaration order. If isType is true, t is used in a method expression and
+// only the methods are returned.
+func (c *cursor) members(t types.Type, isType bool) []types.Object {
+	if tp, ok := t.(*types.TypeParam); ok {
+		t = tp.Constraint()
+	}
+	var (
+		objs []types.Object
+		seen = make(map[string]bool)
+	)
+	if !isType {
+		// visit the embedded structs breadth-first, so the shallower fields
+		// shadow the deeper ones.
+		visited := make(map[types.Type]bool)
+		for queue := []types.Type{t}; len(queue) > 0; {
+			var next []types.Type
+			for _, t := range queue {
+				st, ok := deref(t).Underlying().(*types.Struct)
+				if !ok || visited[t] {
+					continue
 				}
-				if d, ok := t.(*ast.GenDecl); ok {
-					for _, t := range d.Specs {
-						if v, ok := t.(*ast.ValueSpec); ok {
-							// FIXME loop over all names in case there's multiple assignement
-							if v.Names[0].Name == selectors[0] {
-								if len(v.Values) > 0 {
-									switch vs := v.Values[0].(type) { //nolint:gocritic
-									case *ast.CompositeLit:
-										// related test-cases:
-										// - cmd/gnols/testdata/document_completion_local_struct_global_var.txtar
-										typ := nodeName(vs.Type)
-										syms = symbolFinder{h.currentPkg.Symbols}.find(append([]string{typ}, selectors[1:]...))
-									}
-								} else {
-									// related test-cases:
-									// - cmd/gnols/testdata/document_completion_local_inline_struct.txtar
-									// TODO address when len(selectors)>1
-									for _, field := range v.Type.(*ast.StructType).Fields.List {
-										syms = append(syms, gno.Symbol{
-											Name:      field.Names[0].Name,
-											Kind:      "field",
-											Doc:       strings.TrimSpace(field.Doc.Text()),
-											Signature: doc.Content[field.Pos()-1 : field.End()-1],
-										})
-									}
-								}
-							}
-						}
+				visited[t] = true
+				for i := 0; i < st.NumFields(); i++ {
+					f := st.Field(i)
+					if !seen[f.Name()] {
+						seen[f.Name()] = true
+						objs = append(objs, f)
+					}
+					if f.Embedded() {
+						next = append(next, f.Type())
 					}
 				}
 			}
+			queue = next
+		}
+	}
+
+	// include the methods with pointer receivers, since the value is
+	// likely addressable.
+	mt := t
+	if _, ok := t.Underlying().(*types.Interface); !ok {
+		if _, ok := t.(*types.Pointer); !ok {
+			mt = types.NewPointer(t)
+		}
+	}
+	var (
+		mset    = types.NewMethodSet(mt)
+		methods []types.Object
+	)
+	for i := 0; i < mset.Len(); i++ {
+		if m := mset.At(i).Obj(); !seen[m.Name()] {
+			methods = append(methods, m)
+		}
+	}
+	sort.SliceStable(methods, func(i, j int) bool { return methods[i].Pos() < methods[j].Pos() })
+	return append(objs, methods...)
+}
+
+// objectItems returns the items of the objects visible from the current
+// package and matching prefix. owner is the name of the type of the fields
+// and methods in objs, if any.
+func (c *cursor) objectItems(objs []types.Object, owner, prefix string) []protocol.CompletionItem {
+	items := []protocol.CompletionItem{}
+	for _, obj := range objs {
+		if !strings.HasPrefix(obj.Name(), prefix) {
+			continue
+		}
+		if !obj.Exported() && obj.Pkg() != nil && obj.Pkg() != c.pkg.Types {
+			continue
 		}
+		items = append(items, c.objectItem(obj, owner))
+	}
+	return items
+}
 
-		if len(syms) > 0 {
-			spew.Dump("FOUND", syms)
-			for _, f := range syms {
-				items = append(items, protocol.CompletionItem{
-					Label:         f.Name,
-					InsertText:    f.Name,
-					Kind:          symbolToKind(f.Kind),
-					Detail:        f.Signature,
-					Documentation: f.Doc,
-				})
+// objectItem returns the item of obj, whose symbol is kept for the
+// resolution of the item.
+func (c *cursor) objectItem(obj types.Object, owner string) protocol.CompletionItem {
+	var (
+		id  = objectID(obj, owner)
+		sym = c.symbolOf(obj)
+	)
+	c.remember(id, sym, c.objectLink(obj))
+	return symbolItem(id, sym)
+}
+
+// symbolOf returns the symbol of obj, from its declaration if available.
+func (c *cursor) symbolOf(obj types.Object) gno.Symbol {
+	if sym := c.chk.DeclSymbol(obj); sym != nil {
+		switch obj := obj.(type) {
+		case *types.Var:
+			// parameters are declared by fields
+			if !obj.IsField() {
+				sym.Kind = "var"
 			}
-			break
+		case *types.Const:
+			sym.Kind = "const"
+		}
+		return *sym
+	}
+	sym := gno.Symbol{
+		Name:      obj.Name(),
+		Signature: types.ObjectString(obj, types.RelativeTo(c.pkg.Types)),
+	}
+	switch obj := obj.(type) {
+	case *types.Func:
+		sym.Kind = "func"
+		if sig, ok := obj.Type().(*types.Signature); ok && sig.Recv() != nil {
+			sym.Kind = "method"
+		}
+	case *types.Var:
+		sym.Kind = "var"
+		if obj.IsField() {
+			sym.Kind = "field"
+		}
+	case *types.Const:
+		sym.Kind = "const"
+	case *types.TypeName:
+		sym.Kind = "type"
+	case *types.Builtin:
+		sym.Kind = "func"
+	case *types.PkgName:
+		sym.Kind = "package"
+		sym.Signature = fmt.Sprintf("import %q", obj.Imported().Path())
+	}
+	return sym
+}
+
+// indexItems returns the items of the symbols matching sel from the symbol
+// indexes. It's used when the type of sel.X is unknown, typically because it
+// comes from a package that isn't imported or whose sources aren't available.
+func (c *cursor) indexItems(sel *ast.SelectorExpr, prefix string) []protocol.CompletionItem {
+	selectors := []string{prefix}
+	x := sel.X
+loop:
+	for {
+		switch e := x.(type) {
+		case *ast.SelectorExpr:
+			selectors = append([]string{e.Sel.Name}, selectors...)
+			x = e.X
+		case *ast.CallExpr:
+			// symbolFinder resolves functions to their result type
+			x = e.Fun
+		case *ast.ParenExpr:
+			x = e.X
+		case *ast.StarExpr:
+			x = e.X
+		default:
+			break loop
 		}
 	}
+	root, ok := x.(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	switch obj := c.pkg.Info.Uses[root].(type) {
+	case nil:
+		// root may be a package not imported yet, offer all the packages
+		// with this name.
+		var items []protocol.CompletionItem
+		for _, pkg := range c.pkgsNamed(root.Name) {
+			items = append(items, c.pkgItems(pkg, selectors)...)
+		}
+		return items
+
+	case *types.PkgName:
+		if pkg := c.h.lookupIndexedPkg(obj.Imported().Path()); pkg != nil {
+			return c.pkgItems(pkg, selectors)
+		}
 
-	/*
-		//-----------------------------------------
-		// Look up local symbols
-		if syms := h.lookupSymbols(selectors); len(syms) > 0 {
-			for _, f := range syms {
-				items = append(items, protocol.CompletionItem{
-					Label:         f.Name,
-					InsertText:    f.Name,
-					Kind:          symbolToKind(f.Kind),
-					Detail:        f.Signature,
-					Documentation: f.Doc,
-				})
+	case *types.Var:
+		// the variable type is unknown, look up its declared type
+		switch typ := c.declType(obj).(type) {
+		case *ast.Ident:
+			return c.indexSymbolItems(&c.ws.currentPkg, symbolFinder{c.ws.currentPkg.Symbols}.find(append([]string{typ.Name}, selectors...)))
+		case *ast.SelectorExpr:
+			id, ok := typ.X.(*ast.Ident)
+			if !ok {
+				return nil
 			}
-		} else {
-			//-----------------------------------------
-			// Look up stdlib
-			if pkg := lookupPkg(stdlib.Packages, selectors[0]); pkg != nil {
-				for _, s := range pkg.Symbols {
-					if s.Recv != "" {
-						// skip symbols with receiver (methods)
-						continue
-					}
-					if len(selectors) > 1 && !strings.HasPrefix(s.Name, selectors[1]) {
-						// TODO handle multiple selectors? (possible if for example a global
-						// var is defined in the pkg, and the user is referrencing it.)
+			if pkg := c.pkgOf(id); pkg != nil {
+				return c.indexSymbolItems(pkg, exported(symbolFinder{pkg.Symbols}.find(append([]string{typ.Sel.Name}, selectors...))))
+			}
+		}
+	}
+	return nil
+}
 
-						// skip symbols that doesn't match the prefix
-						continue
-					}
-					items = append(items, protocol.CompletionItem{
-						Label:         s.Name,
-						InsertText:    s.Name,
-						Kind:          symbolToKind(s.Kind),
-						Detail:        s.Signature,
-						Documentation: s.Doc,
-					})
+// findInIndex returns the items of the symbols matching the selectors of name,
+// name being either a package or a symbol of the current package.
+func (c *cursor) findInIndex(name string, selectors []string) []protocol.CompletionItem {
+	if pkgs := c.pkgsNamed(name); len(pkgs) > 0 {
+		var items []protocol.CompletionItem
+		for _, pkg := range pkgs {
+			items = append(items, c.pkgItems(pkg, selectors)...)
+		}
+		return items
+	}
+	return c.indexSymbolItems(&c.ws.currentPkg, symbolFinder{c.ws.currentPkg.Symbols}.find(append([]string{name}, selectors...)))
+}
+
+// pkgItems returns the items of the exported symbols of pkg matching
+// selectors. If pkg isn't imported yet, the items include the edit adding the
+// import.
+func (c *cursor) pkgItems(pkg *gno.Package, selectors []string) []protocol.CompletionItem {
+	items := c.indexSymbolItems(pkg, exported(symbolFinder{pkg.Symbols}.find(selectors)))
+	if !c.imports(pkg.ImportPath) {
+		edit := c.importEdit(pkg.ImportPath)
+		for i := range items {
+			items[i].AdditionalTextEdits = []protocol.TextEdit{edit}
+		}
+	}
+	return items
+}
+
+// exported returns the exported symbols of syms.
+func exported(syms []gno.Symbol) []gno.Symbol {
+	var res []gno.Symbol
+	for _, sym := range syms {
+		if token.IsExported(sym.Name) {
+			res = append(res, sym)
+		}
+	}
+	return res
+}
+
+// imports returns true if the file imports path.
+func (c *cursor) imports(path string) bool {
+	for _, spec := range c.file.Imports {
+		if p, err := strconv.Unquote(spec.Path.Value); err == nil && p == path {
+			return true
+		}
+	}
+	return false
+}
+
+// importEdit returns the edit adding the import of path to the file: in the
+// grouped import declaration if any, otherwise after the last import or the
+// package clause.
+func (c *cursor) importEdit(path string) protocol.TextEdit {
+	var (
+		tf     = c.chk.Fset.File(c.file.Pos())
+		quoted = strconv.Quote(path)
+		last   *ast.GenDecl
+	)
+	for _, decl := range c.file.Decls {
+		d, ok := decl.(*ast.GenDecl)
+		if !ok || d.Tok != token.IMPORT {
+			continue
+		}
+		if d.Lparen.IsValid() && d.Rparen.IsValid() {
+			pos := c.doc.OffsetToPosition(tf.Offset(d.Rparen))
+			if pos.Character == 0 {
+				// ")" is at the beginning of the line
+				return protocol.TextEdit{
+					Range:   protocol.Range{Start: pos, End: pos},
+					NewText: "\t" + quoted + "\n",
 				}
 			}
+			return protocol.TextEdit{
+				Range:   protocol.Range{Start: pos, End: pos},
+				NewText: "\n\t" + quoted + "\n",
+			}
 		}
-	*/
-	return reply(ctx, items, nil)
+		last = d
+	}
+	if last != nil {
+		pos := c.doc.OffsetToPosition(tf.Offset(last.End()))
+		return protocol.TextEdit{
+			Range:   protocol.Range{Start: pos, End: pos},
+			NewText: "\nimport " + quoted,
+		}
+	}
+	pos := c.doc.OffsetToPosition(tf.Offset(c.file.Name.End()))
+	return protocol.TextEdit{
+		Range:   protocol.Range{Start: pos, End: pos},
+		NewText: "\n\nimport " + quoted,
+	}
+}
+
+// textSelectors returns the selectors of the expression typed before the
+// cursor, e.g. ["x", "Foo", "B"] for "x.Foo.B".
+func (c *cursor) textSelectors() []string {
+	start := c.offset
+	for start > 0 {
+		r, size := utf8.DecodeLastRuneInString(c.doc.Content[:start])
+		if r != '.' && r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+		start -= size
+	}
+	return strings.Split(c.doc.Content[start:c.offset], ".")
 }
 
-/* TODO remove me when astutil.PathEnclosingInterval is proven to be the
- best way to perform code completion
+// declType returns the type expression of the declaration of v, or nil if
+// it can't be determined.
+func (c *cursor) declType(v *types.Var) ast.Expr {
+	var typ ast.Expr
+loop:
+	for _, n := range c.chk.DeclPath(v) {
+		switch n := n.(type) {
+		case *ast.Field:
+			typ = n.Type
+			break loop
+		case *ast.ValueSpec:
+			typ = n.Type
+			if typ == nil {
+				typ = valueOf(v, n.Names, n.Values)
+			}
+			break loop
+		case *ast.AssignStmt:
+			var lhs []*ast.Ident
+			for _, e := range n.Lhs {
+				id, _ := e.(*ast.Ident)
+				lhs = append(lhs, id)
+			}
+			typ = valueOf(v, lhs, n.Rhs)
+			break loop
+		case *ast.BlockStmt, *ast.File:
+			return nil
+		}
+	}
+	for {
+		switch e := typ.(type) {
+		case *ast.CompositeLit:
+			typ = e.Type
+		case *ast.UnaryExpr:
+			typ = e.X
+		case *ast.StarExpr:
+			typ = e.X
+		default:
+			return typ
+		}
+	}
+}
 
-func (h handler) lookupSymbols(selectors []string) []gno.Symbol {
-	// Check first in current pkg
-	found := symbolFinder{h.currentPkg.Symbols}.find(selectors)
-	if len(found) > 0 {
-		return found
+// valueOf returns the value assigned to v in a declaration.
+func valueOf(v *types.Var, names []*ast.Ident, values []ast.Expr) ast.Expr {
+	if len(names) != len(values) {
+		return nil
 	}
-	// Check in sub packages
-	for _, pkg := range h.subPkgs {
-		if pkg.Name == selectors[0] {
-			return symbolFinder{pkg.Symbols}.find(selectors[1:])
+	for i, id := range names {
+		if id != nil && id.Pos() == v.Pos() {
+			return values[i]
 		}
 	}
-	// TODO check in imported packages of the file
-	return []gno.Symbol{}
+	return nil
+}
+
+func deref(t types.Type) types.Type {
+	if p, ok := t.(*types.Pointer); ok {
+		return p.Elem()
+	}
+	return t
 }
-*/
 
 type symbolFinder struct {
 	baseSymbols []gno.Symbol
@@ -318,7 +942,7 @@ func (s symbolFinder) findIn(symbols []gno.Symbol, selectors []string) []gno.Sym
 			slog.Info("found symbol", "name", name, "kind", sym.Kind, "type", sym.Type, "selectors", selectors)
 			// we found a symbol matching name
 			switch sym.Kind {
-			case "var", "field":
+			case "var", "const", "field":
 				if sym.Type == "" {
 					// sym is an inline struct, returns fields
 					// TODO ensure that works when there's still other selectors
@@ -343,10 +967,3 @@ func (s symbolFinder) findIn(symbols []gno.Symbol, selectors []string) []gno.Sym
 	}
 	return syms
 }
-
-func nodeName(n ast.Node) string {
-	if id, ok := n.(*ast.Ident); ok {
-		return id.Name
-	}
-	return ""
-}
@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.lsp.dev/protocol"
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// expectedType returns the type of the value expected at the cursor, which is
+// the type of the matching result when the cursor is in a return statement.
+func (c *cursor) expectedType() types.Type {
+	ret := c.returnStmt()
+	if ret == nil {
+		return nil
+	}
+	sig := c.enclosingSignature()
+	if sig == nil {
+		return nil
+	}
+	// the index of the result at the cursor
+	i := 0
+	for _, r := range ret.Results {
+		if r.End() < c.pos {
+			i++
+		}
+	}
+	if i >= sig.Results().Len() {
+		return nil
+	}
+	return sig.Results().At(i).Type()
+}
+
+// returnStmt returns the return statement containing the cursor, including
+// the one ending before the cursor on the same line, like "return |".
+func (c *cursor) returnStmt() *ast.ReturnStmt {
+	path, _ := astutil.PathEnclosingInterval(c.file, c.pos, c.pos)
+	for _, n := range path {
+		switch n := n.(type) {
+		case *ast.ReturnStmt:
+			return n
+		case *ast.BlockStmt:
+			tf := c.chk.Fset.File(c.file.Pos())
+			for _, stmt := range n.List {
+				if ret, ok := stmt.(*ast.ReturnStmt); ok && ret.Pos() < c.pos && tf.Line(ret.Pos()) == tf.Line(c.pos) {
+					return ret
+				}
+			}
+			return nil
+		case *ast.FuncDecl, *ast.FuncLit:
+			return nil
+		}
+	}
+	return nil
+}
+
+// matches returns true if obj is a value which can be used where a value of
+// type expected is expected.
+func matches(obj types.Object, expected types.Type) bool {
+	switch obj := obj.(type) {
+	case *types.Var, *types.Const, *types.Nil:
+		return types.AssignableTo(obj.Type(), expected)
+	case *types.Func:
+		sig, ok := obj.Type().(*types.Signature)
+		return ok && sig.Results().Len() == 1 && types.AssignableTo(sig.Results().At(0).Type(), expected)
+	}
+	return false
+}
+
+// typeCaseAt returns the type switch whose case clause list contains the
+// cursor.
+func (c *cursor) typeCaseAt() *ast.TypeSwitchStmt {
+	path, _ := astutil.PathEnclosingInterval(c.file, c.pos, c.pos)
+	for i, n := range path {
+		cc, ok := n.(*ast.CaseClause)
+		if !ok {
+			continue
+		}
+		tf := c.chk.Fset.File(c.file.Pos())
+		if !strings.HasPrefix(c.doc.Content[tf.Offset(cc.Case):], "case") {
+			// default clause
+			return nil
+		}
+		if c.pos < cc.Case+token.Pos(len("case ")) || c.pos > cc.Colon || i+2 >= len(path) {
+			return nil
+		}
+		if ts, ok := path[i+2].(*ast.TypeSwitchStmt); ok {
+			return ts
+		}
+		return nil
+	}
+	return nil
+}
+
+// typeCaseItems returns the items of the types implementing the interface of
+// the type switch ts, which aren't listed in its cases yet.
+func (c *cursor) typeCaseItems(ts *ast.TypeSwitchStmt, prefix string) []protocol.CompletionItem {
+	var x ast.Expr
+	switch s := ts.Assign.(type) {
+	case *ast.AssignStmt:
+		if len(s.Rhs) == 1 {
+			x = s.Rhs[0]
+		}
+	case *ast.ExprStmt:
+		x = s.X
+	}
+	ta, ok := x.(*ast.TypeAssertExpr)
+	if !ok {
+		return nil
+	}
+	t := c.pkg.Info.TypeOf(ta.X)
+	if t == nil {
+		// the switched expression doesn't resolve
+		return nil
+	}
+	iface, ok := t.Underlying().(*types.Interface)
+	if !ok {
+		return nil
+	}
+
+	// skip the types already listed
+	listed := make(map[string]bool)
+	for _, stmt := range ts.Body.List {
+		for _, e := range stmt.(*ast.CaseClause).List {
+			if e.End() < c.pos || e.Pos() > c.pos {
+				listed[types.ExprString(e)] = true
+			}
+		}
+	}
+	var (
+		items = []protocol.CompletionItem{}
+		add   = func(obj types.Object, qual string) {
+			tn, ok := obj.(*types.TypeName)
+			if !ok || tn.Name() == "_" || tn.Name() == "comparable" {
+				return
+			}
+			if named, ok := tn.Type().(*types.Named); ok && named.TypeParams().Len() > 0 {
+				// generic types must be instantiated
+				return
+			}
+			name := tn.Name()
+			if qual != "" {
+				name = qual + "." + name
+			}
+			switch {
+			case types.Implements(tn.Type(), iface):
+			case !types.IsInterface(tn.Type()) && types.Implements(types.NewPointer(tn.Type()), iface):
+				name = "*" + name
+			default:
+				return
+			}
+			if listed[name] || !strings.HasPrefix(strings.TrimPrefix(name, "*"), prefix) {
+				return
+			}
+			item := c.objectItem(obj, "")
+			item.Label, item.InsertText = name, name
+			items = append(items, item)
+		}
+	)
+	scope := c.pkg.Types.Scope()
+	for _, name := range scope.Names() {
+		add(scope.Lookup(name), "")
+	}
+	for _, spec := range c.file.Imports {
+		pn := c.pkg.Info.PkgNameOf(spec)
+		if pn == nil || pn.Name() == "_" || pn.Name() == "." {
+			continue
+		}
+		if path, err := strconv.Unquote(spec.Path.Value); err != nil || !c.chk.Loaded(path) {
+			continue
+		}
+		s := pn.Imported().Scope()
+		for _, name := range s.Names() {
+			if obj := s.Lookup(name); obj.Exported() {
+				add(obj, pn.Name())
+			}
+		}
+	}
+	for _, name := range types.Universe.Names() {
+		add(types.Universe.Lookup(name), "")
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Label < items[j].Label })
+	return items
+}
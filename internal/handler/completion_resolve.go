@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/types"
+	"log/slog"
+	"strings"
+
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+
+	"github.com/jdkato/gnols/internal/gno"
+)
+
+// gnoRepoURL is the URL of the sources of the Gno standard library and
+// examples, linked from the documentation of their symbols.
+const gnoRepoURL = "https://github.com/gnolang/gno/tree/master"
+
+// completionData is attached to the completion items, it contains what
+// `completionItem/resolve` needs to add the documentation.
+type completionData struct {
+	// ID identifies the symbol or the package of the item, see gno.SymbolID.
+	ID string `json:"id"`
+}
+
+// completedSymbol is a symbol of the last completion which can't be found in
+// the stdlib index, with the location of its source.
+type completedSymbol struct {
+	sym  gno.Symbol
+	link string
+}
+
+func (h *handler) handleCompletionItemResolve(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var item protocol.CompletionItem
+	if err := readParams(req, &item); err != nil {
+		return replyErr(ctx, reply, err)
+	}
+	item, err := h.resolveCompletionItem(item)
+	if err != nil {
+		return replyErr(ctx, reply, err)
+	}
+	return reply(ctx, item, nil)
+}
+
+func (h *handler) resolveCompletionItem(item protocol.CompletionItem) (protocol.CompletionItem, error) {
+	if item.Data == nil {
+		// nothing to resolve
+		return item, nil
+	}
+	// item.Data is decoded as a map, convert it back to completionData
+	bz, err := json.Marshal(item.Data)
+	if err != nil {
+		return item, err
+	}
+	var data completionData
+	if err := json.Unmarshal(bz, &data); err != nil {
+		return item, fmt.Errorf("invalid completion item data: %w", err)
+	}
+	slog.Info("completion resolve", "data", data)
+
+	cs, ok := h.completed[data.ID]
+	if !ok {
+		pkg, sym := gno.LookupSymbol(h.stdlibPkgs(), data.ID)
+		if pkg == nil {
+			// unknown symbol, leave the item as is
+			return item, nil
+		}
+		cs.link, _ = h.pkgLink(pkg.ImportPath)
+		if sym != nil {
+			cs.sym = *sym
+			cs.link = symbolLink(cs.link, *sym)
+		} else {
+			cs.sym = gno.Symbol{Name: pkg.Name, Kind: "package", Doc: pkg.Doc}
+		}
+	}
+	if cs.sym.Signature != "" {
+		item.Detail = cs.sym.Signature
+	}
+	item.Documentation = protocol.MarkupContent{
+		Kind:  protocol.Markdown,
+		Value: completionDoc(cs.sym, cs.link),
+	}
+	return item, nil
+}
+
+// completionDoc returns the markdown documentation of sym, whose source is at
+// link.
+func completionDoc(sym gno.Symbol, link string) string {
+	var parts []string
+	if sym.Signature != "" {
+		parts = append(parts, fmt.Sprintf("```gno\n%s\n```", sym.Signature))
+	}
+	if doc := strings.TrimSpace(sym.Doc); doc != "" {
+		parts = append(parts, doc)
+	}
+	if link != "" {
+		parts = append(parts, fmt.Sprintf("[Source](%s)", link))
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// remember keeps sym, identified by id, for the resolution of the items of
+// the current completion.
+func (c *cursor) remember(id string, sym gno.Symbol, link string) {
+	if c.h.completed != nil {
+		c.h.completed[id] = completedSymbol{sym: sym, link: link}
+	}
+}
+
+// rememberPkg keeps the documentation of the package imported with path if
+// it's a workspace package, which isn't in the stdlib index.
+func (c *cursor) rememberPkg(path, name, doc string) {
+	if link, own := c.h.pkgLink(path); own {
+		c.remember(path, gno.Symbol{Name: name, Kind: "package", Doc: doc}, link)
+	}
+}
+
+// pkgLink returns the location of the sources of the package imported with
+// path, and true if it's a workspace package.
+func (h *handler) pkgLink(path string) (string, bool) {
+	for _, pkg := range h.workspacePkgs() {
+		if pkg.ImportPath == path {
+			return string(uri.File(pkg.Dir)), true
+		}
+	}
+	if strings.HasPrefix(path, "gno.land/") {
+		return gnoRepoURL + "/examples/" + path, false
+	}
+	return gnoRepoURL + "/gnovm/stdlibs/" + path, false
+}
+
+// symbolLink returns the location of the declaration of sym, an indexed
+// symbol of the package whose sources are at pkgLink. It's pkgLink if the
+// position of sym isn't indexed.
+func symbolLink(pkgLink string, sym gno.Symbol) string {
+	if sym.Pos == nil || sym.Pos.File == "" {
+		return pkgLink
+	}
+	return fmt.Sprintf("%s/%s#L%d", pkgLink, sym.Pos.File, sym.Pos.Line)
+}
+
+// objectID returns the ID of obj, following the format of gno.SymbolID.
+// owner is the name of the type of obj if it's a field, since fields don't
+// reference their struct.
+func objectID(obj types.Object, owner string) string {
+	switch obj := obj.(type) {
+	case *types.PkgName:
+		return obj.Imported().Path()
+	case *types.Func:
+		if sig, ok := obj.Type().(*types.Signature); ok && sig.Recv() != nil {
+			if named, ok := deref(sig.Recv().Type()).(*types.Named); ok {
+				owner = named.Obj().Name()
+			}
+		}
+	case *types.Var:
+		if !obj.IsField() {
+			owner = ""
+		}
+	default:
+		owner = ""
+	}
+	path := "builtin"
+	if obj.Pkg() != nil {
+		path = obj.Pkg().Path()
+	}
+	if owner != "" {
+		return path + "." + owner + "." + obj.Name()
+	}
+	return path + "." + obj.Name()
+}
+
+// objectLink returns the location of the declaration of obj, or an empty
+// string if obj isn't declared in the sources.
+func (c *cursor) objectLink(obj types.Object) string {
+	if !obj.Pos().IsValid() {
+		return ""
+	}
+	pos := c.chk.Fset.Position(obj.Pos())
+	return fmt.Sprintf("%s#L%d", uri.File(pos.Filename), pos.Line)
+}
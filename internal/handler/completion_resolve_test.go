@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"strings"
+	"testing"
+
+	"go.lsp.dev/protocol"
+
+	"github.com/jdkato/gnols/internal/gno"
+)
+
+func TestCompletionResolveSymbolLink(t *testing.T) {
+	var (
+		pos = &gno.Position{File: "tree.gno", Line: 12, Column: 6}
+		avl = gno.Package{
+			Name:       "avl",
+			ImportPath: "gno.land/p/demo/avl",
+			Symbols:    []gno.Symbol{{Name: "NewTree", Signature: "func NewTree() *Tree", Kind: "func", Pos: pos}},
+		}
+		sub = gno.Package{
+			Name:       "sub",
+			ImportPath: "foo/sub",
+			Dir:        "/src/foo/sub",
+			Symbols:    []gno.Symbol{{Name: "Bye", Signature: "func Bye()", Kind: "func", Pos: pos}},
+		}
+		h = &handler{
+			workspaces: []*workspace{{folder: "/src/foo", subPkgs: []gno.Package{sub}}},
+			completed:  make(map[string]completedSymbol),
+		}
+	)
+	h.stdlibIndex.rootPkgs = []gno.Package{avl}
+	c := &cursor{h: h}
+
+	tests := []struct {
+		name string
+		item protocol.CompletionItem
+		want string
+	}{
+		{
+			name: "stdlib",
+			item: symbolItem(avl.SymbolID(avl.Symbols[0]), avl.Symbols[0]),
+			want: "[Source](https://github.com/gnolang/gno/tree/master/examples/gno.land/p/demo/avl/tree.gno#L12)",
+		},
+		{
+			name: "workspace",
+			item: c.indexSymbolItems(&sub, sub.Symbols)[0],
+			want: "[Source](file:///src/foo/sub/tree.gno#L12)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item, err := h.resolveCompletionItem(tt.item)
+			if err != nil {
+				t.Fatal(err)
+			}
+			doc, _ := item.Documentation.(protocol.MarkupContent)
+			if !strings.HasSuffix(doc.Value, tt.want) {
+				t.Errorf("want link %s, got %q", tt.want, doc.Value)
+			}
+		})
+	}
+
+	// the package is linked if the position isn't indexed
+	if link := symbolLink("file:///src/foo/sub", gno.Symbol{Name: "Bye"}); link != "file:///src/foo/sub" {
+		t.Errorf("want the package link, got %s", link)
+	}
+}
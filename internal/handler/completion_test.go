@@ -1,21 +1,24 @@
 package handler
 
 import (
+	"slices"
 	"testing"
 
+	"github.com/jdkato/gnols/internal/gno"
 	"github.com/jdkato/gnols/internal/stdlib"
 )
 
 func TestLookupPkg(t *testing.T) {
-	pkg := lookupPkg(stdlib.Packages, "fmt")
-	if pkg != nil {
-		t.Errorf("Expected nil, got %v", pkg)
+	pkgs := lookupPkgs(stdlib.Packages, "fmt")
+	if len(pkgs) != 0 {
+		t.Errorf("Expected no package, got %v", pkgs)
 	}
 
-	pkg = lookupPkg(stdlib.Packages, "ufmt")
-	if pkg == nil {
-		t.Errorf("Expected non-nil, got %v", pkg)
+	pkgs = lookupPkgs(stdlib.Packages, "ufmt")
+	if len(pkgs) != 1 {
+		t.Fatalf("Expected 1 package, got %v", len(pkgs))
 	}
+	pkg := pkgs[0]
 
 	if pkg.ImportPath != "gno.land/p/demo/ufmt" {
 		t.Errorf("Expected gno.land/p/demo/ufmt, got %v", pkg.ImportPath)
@@ -24,4 +27,54 @@ func TestLookupPkg(t *testing.T) {
 	if len(pkg.Symbols) < 1 {
 		t.Errorf("Expected symbols, got %v", len(pkg.Symbols))
 	}
+
+	// several packages share the same name
+	var paths []string
+	for _, pkg := range lookupPkgs(stdlib.Packages, "users") {
+		paths = append(paths, pkg.ImportPath)
+	}
+	want := []string{"gno.land/p/demo/users", "gno.land/r/demo/users", "gno.land/r/sys/users"}
+	if !slices.Equal(paths, want) {
+		t.Errorf("Expected %v, got %v", want, paths)
+	}
+}
+
+func TestLookupIndexedPkg(t *testing.T) {
+	h := &handler{}
+	tests := []struct {
+		path   string
+		symbol string
+		found  bool
+	}{
+		{path: "fmt", symbol: "Sprintf"},
+		// packages are matched by import path, not by name
+		{path: "ufmt", symbol: "Sprintf"},
+		{path: "gno.land/p/demo/ufmt", symbol: "Sprintf", found: true},
+		{path: "unicode", symbol: "IsDigit", found: true},
+		{path: "unicode", symbol: "FullRune"},
+		{path: "unicode/utf8", symbol: "FullRune", found: true},
+		{path: "unicode/utf8", symbol: "IsDigit"},
+	}
+	for _, tt := range tests {
+		found := false
+		if pkg := h.lookupIndexedPkg(tt.path); pkg != nil {
+			found = slices.ContainsFunc(pkg.Symbols, func(s gno.Symbol) bool { return s.Name == tt.symbol })
+		}
+		if found != tt.found {
+			t.Errorf("%s.%s: expected found = %v, got %v", tt.path, tt.symbol, tt.found, found)
+		}
+	}
+}
+
+func TestCompletionTriggers(t *testing.T) {
+	triggers := completionTriggers()
+	// selectors, import paths and identifiers
+	for _, want := range []string{".", "\"", "/", "_", "a", "z", "A", "Z"} {
+		if !slices.Contains(triggers, want) {
+			t.Errorf("missing trigger %q in %q", want, triggers)
+		}
+	}
+	if slices.Contains(triggers, "0") {
+		t.Errorf("unexpected digit trigger in %q", triggers)
+	}
 }
@@ -2,7 +2,10 @@ package handler
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
 
 	"go.lsp.dev/jsonrpc2"
 	"go.lsp.dev/protocol"
@@ -30,12 +33,71 @@ func (h *handler) handleDidChangeConfiguration(ctx context.Context, reply jsonrp
 	build, _ := settings["buildOnSave"].(bool)
 	root, _ := settings["root"].(string)
 
-	var err error
-	h.binManager, err = gno.NewBinManager(h.workspaceFolder, gnoBin, gnokeyBin, goplsBin, root, transpile, build)
-	if err != nil {
+	h.settings = &binSettings{
+		gno:       gnoBin,
+		gnokey:    gnokeyBin,
+		gopls:     goplsBin,
+		root:      root,
+		transpile: transpile,
+		build:     build,
+	}
+	h.loadRootIndex(root)
+	// the imports may be resolved in the new root
+	h.resetCheckers()
+	if err := h.setBinManagers(); err != nil {
 		return replyErr(ctx, reply, err)
 	}
-	slog.Info("binManager created", "workspaceFolder", h.workspaceFolder)
-	close(h.configLoaded)
+	h.checkGnoVersion(ctx)
+	select {
+	case <-h.configLoaded:
+		// the configuration was already loaded
+	default:
+		close(h.configLoaded)
+	}
 	return reply(ctx, nil, nil)
 }
+
+// checkGnoVersion selects the embedded index of the version of the gno
+// binaries of the workspace folders, and warns the user if there's none.
+func (h *handler) checkGnoVersion(ctx context.Context) {
+	if warning := h.loadWorkspacesVersion(); warning != "" {
+		h.notify(ctx, protocol.MethodWindowShowMessage, &protocol.ShowMessageParams{
+			Message: warning,
+			Type:    protocol.MessageTypeWarning,
+		})
+	}
+}
+
+// loadWorkspacesVersion selects the embedded index of the gno version of the
+// workspace folders. There's a single index, so it returns a warning if the
+// folders use different versions, besides the one of loadVersionIndex.
+func (h *handler) loadWorkspacesVersion() (warning string) {
+	h.workspacesMu.RLock()
+	var bms []*gno.BinManager
+	for _, ws := range h.workspaces {
+		if ws.binManager != nil {
+			bms = append(bms, ws.binManager)
+		}
+	}
+	h.workspacesMu.RUnlock()
+	var versions []string
+	for _, m := range bms {
+		version, err := m.Version()
+		if err != nil {
+			slog.Error("gno version", "err", err)
+			continue
+		}
+		if !slices.Contains(versions, version) {
+			versions = append(versions, version)
+		}
+	}
+	if len(versions) == 0 {
+		return ""
+	}
+	warning = h.loadVersionIndex(versions[0])
+	if len(versions) > 1 {
+		warning = strings.TrimSpace(fmt.Sprintf("The workspace folders use different gno versions (%s), completion and hover use the symbol index of gno %s. %s",
+			strings.Join(versions, ", "), versions[0], warning))
+	}
+	return warning
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"go.lsp.dev/protocol"
+)
+
+// methodCoverage is the custom notification used to send the uncovered ranges
+// of a document, so editor plugins can shade them.
+const methodCoverage = "gnols/coverage"
+
+type coverageParams struct {
+	URI    protocol.DocumentURI `json:"uri"`
+	Ranges []protocol.Range     `json:"ranges"`
+}
+
+// runCoverage toggles the coverage of pkg in the background, and notifies the
+// client if it fails.
+func (h *handler) runCoverage(ctx context.Context, pkg string) {
+	if err := h.toggleCoverage(ctx, pkg); err != nil {
+		h.notifyErr(ctx, fmt.Errorf("coverage %s: %w", pkg, err))
+	}
+}
+
+// toggleCoverage runs the tests of pkg with coverage enabled and notifies the
+// uncovered ranges of each file. If the coverage of pkg is already displayed,
+// it is cleared instead.
+func (h *handler) toggleCoverage(ctx context.Context, pkg string) error {
+	// a toggle waits for the previous one, so the coverage of a package isn't
+	// run twice
+	h.coverageMu.Lock()
+	defer h.coverageMu.Unlock()
+	if uris, ok := h.coverage[pkg]; ok {
+		slog.Info("coverage", "pkg", pkg, "clear", len(uris))
+		for _, u := range uris {
+			h.notify(ctx, methodCoverage, coverageParams{URI: u, Ranges: []protocol.Range{}})
+		}
+		delete(h.coverage, pkg)
+		return nil
+	}
+
+	bm, err := h.getBinManager(pkg)
+	if err != nil {
+		return err
+	}
+	spans, err := bm.Coverage(pkg)
+	if err != nil {
+		return err
+	}
+	var (
+		uris   []protocol.DocumentURI
+		ranges = map[protocol.DocumentURI][]protocol.Range{}
+	)
+	for _, span := range spans {
+		if _, ok := ranges[span.URI]; !ok {
+			uris = append(uris, span.URI)
+		}
+		ranges[span.URI] = append(ranges[span.URI], span.ToLocation().Range)
+	}
+	slog.Info("coverage", "pkg", pkg, "files", len(uris), "uncovered", len(spans))
+	for _, u := range uris {
+		h.notify(ctx, methodCoverage, coverageParams{URI: u, Ranges: ranges[u]})
+	}
+	h.coverage[pkg] = uris
+	return nil
+}
@@ -25,11 +25,20 @@ func (h *handler) publishDianostics(ctx context.Context, doc *store.Document) {
 }
 
 func (h *handler) getDiagnostics(doc *store.Document) ([]protocol.Diagnostic, error) {
+	if doc.Mod != nil {
+		return h.modDiagnostics(doc), nil
+	}
 	diagnostics := []protocol.Diagnostic{}
 
 	slog.Info("Lint", "path", doc.Path)
 
-	buildErrs, err := h.getBinManager().Lint()
+	bm, err := h.getBinManager(doc.Path)
+	if err != nil {
+		// outside of a workspace folder, there's nothing to lint
+		slog.Warn("Lint", "err", err)
+		return diagnostics, nil
+	}
+	buildErrs, err := bm.Lint()
 	if err != nil {
 		return diagnostics, err
 	}
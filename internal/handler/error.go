@@ -10,7 +10,10 @@ import (
 	"go.lsp.dev/uri"
 )
 
-var ErrBadSettings = errors.New("bad settings")
+var (
+	ErrBadSettings = errors.New("bad settings")
+	ErrNoWorkspace = errors.New("no workspace folder")
+)
 
 func replyErr(ctx context.Context, reply jsonrpc2.Replier, err error) error {
 	slog.Error(err.Error())
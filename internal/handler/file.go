@@ -19,7 +19,7 @@ func (h *handler) handleTextDocumentDidOpen(ctx context.Context, reply jsonrpc2.
 	if err != nil {
 		return replyErr(ctx, reply, fmt.Errorf("documents.Save uri=%s: %w", params.TextDocument.URI, err))
 	}
-	if err := h.updateSymbols(); err != nil {
+	if err := h.loadSymbols(); err != nil {
 		return replyErr(ctx, reply, err)
 	}
 	h.publishDianostics(ctx, doc)
@@ -54,6 +54,9 @@ func (h *handler) handleTextDocumentDidSave(ctx context.Context, reply jsonrpc2.
 		slog.Info("new doc saved", "path", newDoc.Path)
 		doc = newDoc
 	}
+	if err := h.updateSymbols(doc.Path); err != nil {
+		return replyErr(ctx, reply, err)
+	}
 	h.publishDianostics(ctx, doc)
 	return reply(ctx, nil, nil)
 }
@@ -69,6 +72,10 @@ func (h *handler) handleTextDocumentDidChange(ctx context.Context, reply jsonrpc
 		return replyNoDocFound(ctx, reply, params.TextDocument.URI)
 	}
 	doc.ApplyChanges(params.ContentChanges)
+	if doc.Mod != nil {
+		h.publishDianostics(ctx, doc)
+	}
+	h.refreshCodeLens()
 
 	return reply(ctx, nil, nil)
 }
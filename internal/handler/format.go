@@ -23,7 +23,15 @@ func (h *handler) handleTextDocumentFormatting(ctx context.Context, reply jsonrp
 
 	slog.Info("formatting", "pre", doc.Content)
 
-	formatted, err := h.getBinManager().Format(params.TextDocument.URI.Filename())
+	var (
+		formatted []byte
+		err       error
+	)
+	if doc.Mod != nil {
+		formatted, err = formatMod(doc)
+	} else {
+		formatted, err = h.format(doc.Path)
+	}
 	if err != nil {
 		return replyErr(ctx, reply, fmt.Errorf("formatting: %w", err))
 	}
@@ -47,3 +55,13 @@ func (h *handler) handleTextDocumentFormatting(ctx context.Context, reply jsonrp
 		},
 	}, nil)
 }
+
+// format returns the content of the gno file path formatted by the tool of
+// its workspace folder.
+func (h *handler) format(path string) ([]byte, error) {
+	bm, err := h.getBinManager(path)
+	if err != nil {
+		return nil, err
+	}
+	return bm.Format(path)
+}
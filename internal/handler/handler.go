@@ -4,45 +4,93 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sync"
+	"time"
 
 	"go.lsp.dev/jsonrpc2"
 	"go.lsp.dev/protocol"
 
 	"github.com/jdkato/gnols/internal/gno"
+	"github.com/jdkato/gnols/internal/stdlib"
 	"github.com/jdkato/gnols/internal/store"
 )
 
 type handler struct {
 	connPool  jsonrpc2.Conn
 	documents *store.DocumentStore
-	// currentPkg contains the workspace's symbols
-	currentPkg gno.Package
-	// subPkgs contains sub packages' symbols
-	subPkgs    []gno.Package
-	binManager *gno.BinManager
+	// workspaces contains the workspace folders. It's protected by
+	// workspacesMu, since it's read by the commands running in the
+	// background.
+	workspaces   []*workspace
+	workspacesMu sync.RWMutex
+	// settings is nil until the configuration is loaded.
+	settings *binSettings
 	// initialized becomes true after `initialize` message is received.
 	initialized bool
 	// NOTE(tb): See why [here](https://github.com/tbruyelle/gnols/issues/11)
 	configLoaded chan struct{}
-
-	// workspaceFolder contains the path of the project.
-	workspaceFolder string
+	// codeLensRefresh is true if the client supports the
+	// `workspace/codeLens/refresh` request.
+	codeLensRefresh bool
+	// codeLensTimer sends the pending `workspace/codeLens/refresh` request.
+	codeLensTimer *time.Timer
+	codeLensMu    sync.Mutex
+	// stdlibIndex is the index of the standard libraries and the examples.
+	stdlibIndex stdlibIndex
+	// symbolCache is the on-disk cache of the workspace indexes, nil if
+	// there's no user cache directory.
+	symbolCache *gno.SymbolCache
+	// watchFiles is true if the client supports the dynamic registration of
+	// `workspace/didChangeWatchedFiles`.
+	watchFiles bool
+	// clientCommands contains the commands executed by the client, declared
+	// in the `clientCommands` initialization option. The code lenses of these
+	// commands are only sent to the clients which support them.
+	clientCommands map[string]bool
+	// testResults contains the last result of the tests run with gnols.test,
+	// indexed by testKey. It's protected by testResultsMu, since it's written
+	// by the tests running in the background.
+	testResults   map[string]gno.TestResult
+	testResultsMu sync.Mutex
+	// coverage contains the documents whose coverage is displayed, indexed by
+	// package directory.
+	coverage   map[string][]protocol.DocumentURI
+	coverageMu sync.Mutex
+	// completed contains the symbols of the last completion which can't be
+	// found in the stdlib index, indexed by ID.
+	completed map[string]completedSymbol
 }
 
 func NewHandler(connPool jsonrpc2.Conn) jsonrpc2.Handler {
 	handler := &handler{
 		connPool:     connPool,
 		documents:    store.NewDocumentStore(),
-		binManager:   nil,
 		configLoaded: make(chan struct{}),
+		testResults:  make(map[string]gno.TestResult),
+		coverage:     make(map[string][]protocol.DocumentURI),
+	}
+	if dir := gno.SymbolCacheDir(); dir != "" {
+		handler.symbolCache = gno.NewSymbolCache(dir)
+	}
+	if stdlib.Schema < gno.SchemaVersion {
+		slog.Warn("outdated stdlib index, generate it again with cmd/gen", "schema", stdlib.Schema, "expected", gno.SchemaVersion)
 	}
 	slog.Info("connections opened")
 	return jsonrpc2.ReplyHandler(handler.handle)
 }
 
-func (h *handler) getBinManager() *gno.BinManager {
+// getBinManager returns the BinManager of the workspace folder containing
+// path, once the configuration is loaded. It fails if there's no workspace
+// folder.
+func (h *handler) getBinManager(path string) (*gno.BinManager, error) {
 	<-h.configLoaded
-	return h.binManager
+	ws := h.workspaceOf(path)
+	h.workspacesMu.RLock()
+	defer h.workspacesMu.RUnlock()
+	if ws.binManager == nil {
+		return nil, fmt.Errorf("%s: %w", path, ErrNoWorkspace)
+	}
+	return ws.binManager, nil
 }
 
 func (h *handler) handle(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
@@ -60,9 +108,14 @@ func (h *handler) handle(ctx context.Context, reply jsonrpc2.Replier, req jsonrp
 	}
 	switch req.Method() {
 	case protocol.MethodInitialized:
+		h.registerFileWatchers()
 		return reply(ctx, nil, nil)
 	case protocol.MethodWorkspaceDidChangeConfiguration:
 		return h.handleDidChangeConfiguration(ctx, reply, req)
+	case protocol.MethodWorkspaceDidChangeWorkspaceFolders:
+		return h.handleDidChangeWorkspaceFolders(ctx, reply, req)
+	case protocol.MethodWorkspaceDidChangeWatchedFiles:
+		return h.handleDidChangeWatchedFiles(ctx, reply, req)
 	case protocol.MethodShutdown:
 		return h.handleShutdown(ctx, reply, req)
 	case protocol.MethodTextDocumentDidOpen:
@@ -85,14 +138,20 @@ func (h *handler) handle(ctx context.Context, reply jsonrpc2.Replier, req jsonrp
 		return h.handleTextDocumentRename(ctx, reply, req)
 	case protocol.MethodTextDocumentCompletion:
 		return h.handleTextDocumentCompletion(ctx, reply, req)
+	case protocol.MethodCompletionItemResolve:
+		return h.handleCompletionItemResolve(ctx, reply, req)
 	case protocol.MethodTextDocumentHover:
 		return h.handleHover(ctx, reply, req)
 	case protocol.MethodTextDocumentCodeLens:
 		return h.handleCodeLens(ctx, reply, req)
+	case protocol.MethodCodeLensResolve:
+		return h.handleCodeLensResolve(ctx, reply, req)
 	case protocol.MethodWorkspaceExecuteCommand:
 		return h.handleExecuteCommand(ctx, reply, req)
 	case protocol.MethodTextDocumentFormatting:
 		return h.handleTextDocumentFormatting(ctx, reply, req)
+	case protocol.MethodTextDocumentCodeAction:
+		return h.handleCodeAction(ctx, reply, req)
 	default:
 		return jsonrpc2.MethodNotFoundHandler(ctx, reply, req)
 	}
@@ -104,10 +163,22 @@ func (h *handler) handleInitialize(ctx context.Context, reply jsonrpc2.Replier,
 		return replyErr(ctx, reply, err)
 	}
 	// NOTE(tb): params.RootURI is deprecated in favor of params.WorkspaceFolders,
-	// but this one is not filled by vim-lsp. Maybe we should check it first and
-	// fallback to params.RootURI.
-	h.workspaceFolder = params.RootURI.Filename() //nolint:staticcheck
-	slog.Info("Initialize", "params", params, "workspaceFolder", h.workspaceFolder)
+	// but this one is not filled by vim-lsp, so it's used as a fallback.
+	for _, folder := range workspaceFolders(params) {
+		if err := h.addWorkspace(folder); err != nil {
+			return replyErr(ctx, reply, err)
+		}
+	}
+	slog.Info("Initialize", "params", params, "workspaces", len(h.workspaces))
+	if ws := params.Capabilities.Workspace; ws != nil {
+		if ws.CodeLens != nil {
+			h.codeLensRefresh = ws.CodeLens.RefreshSupport
+		}
+		if ws.DidChangeWatchedFiles != nil {
+			h.watchFiles = ws.DidChangeWatchedFiles.DynamicRegistration
+		}
+	}
+	h.clientCommands = clientCommands(params.InitializationOptions)
 
 	return reply(ctx, protocol.InitializeResult{
 		Capabilities: protocol.ServerCapabilities{
@@ -125,24 +196,52 @@ func (h *handler) handleInitialize(ctx context.Context, reply jsonrpc2.Replier,
 			},
 			ImplementationProvider: &protocol.ImplementationOptions{},
 			CompletionProvider: &protocol.CompletionOptions{
-				TriggerCharacters: []string{"."},
-				ResolveProvider:   false,
+				TriggerCharacters: completionTriggers(),
+				ResolveProvider:   true,
 			},
 			HoverProvider: true,
 			ExecuteCommandProvider: &protocol.ExecuteCommandOptions{
 				Commands: []string{
 					"gnols.gnofmt",
 					"gnols.test",
+					"gnols.run",
+					"gnols.coverage",
 				},
 			},
 			CodeLensProvider: &protocol.CodeLensOptions{
 				ResolveProvider: true,
 			},
 			DocumentFormattingProvider: true,
+			CodeActionProvider: &protocol.CodeActionOptions{
+				CodeActionKinds: []protocol.CodeActionKind{protocol.RefactorRewrite},
+			},
+			Workspace: &protocol.ServerCapabilitiesWorkspace{
+				WorkspaceFolders: &protocol.ServerCapabilitiesWorkspaceFolders{
+					Supported:           true,
+					ChangeNotifications: true,
+				},
+			},
 		},
 	}, nil)
 }
 
+// clientCommands returns the commands listed in the `clientCommands`
+// initialization option, like `{"clientCommands": ["gnols.debugTest"]}`.
+func clientCommands(opts interface{}) map[string]bool {
+	cmds := make(map[string]bool)
+	options, ok := opts.(map[string]interface{})
+	if !ok {
+		return cmds
+	}
+	list, _ := options["clientCommands"].([]interface{})
+	for _, c := range list {
+		if cmd, ok := c.(string); ok {
+			cmds[cmd] = true
+		}
+	}
+	return cmds
+}
+
 func (h *handler) handleShutdown(ctx context.Context, reply jsonrpc2.Replier, _ jsonrpc2.Request) error {
 	return reply(ctx, nil, h.connPool.Close())
 }
@@ -165,20 +264,3 @@ func (h *handler) notifyErr(ctx context.Context, err error) {
 		Type:    protocol.MessageTypeError,
 	})
 }
-
-func (h *handler) updateSymbols() error {
-	pkgs, err := gno.ParsePackages(h.workspaceFolder, h.workspaceFolder)
-	if err != nil {
-		return fmt.Errorf("updateSymbols: %w", err)
-	}
-	h.subPkgs = nil
-	for _, pkg := range pkgs {
-		if pkg.ImportPath == "." {
-			h.currentPkg = pkg
-		} else {
-			h.subPkgs = append(h.subPkgs, pkg)
-		}
-	}
-	slog.Info("update workspace packages", "current", h.currentPkg, "subs", h.subPkgs)
-	return nil
-}
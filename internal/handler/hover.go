@@ -3,13 +3,21 @@ package handler
 import (
 	"context"
 	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
 	"log/slog"
+	"path"
+	"path/filepath"
+	"strconv"
 	"strings"
 
 	"go.lsp.dev/jsonrpc2"
 	"go.lsp.dev/protocol"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/tools/go/ast/astutil"
 
-	"github.com/jdkato/gnols/internal/stdlib"
+	"github.com/jdkato/gnols/internal/gno"
 )
 
 func (h *handler) handleHover(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
@@ -22,67 +30,256 @@ func (h *handler) handleHover(ctx context.Context, reply jsonrpc2.Replier, req j
 	if !ok {
 		return replyNoDocFound(ctx, reply, params.TextDocument.URI)
 	}
-	pgf := doc.Pgf
-
+	if doc.Mod != nil {
+		if hover := h.modHover(doc, params.Position); hover != nil {
+			return reply(ctx, hover, nil)
+		}
+		return reply(ctx, nil, nil)
+	}
 	offset := doc.PositionToOffset(params.Position)
-	// tokedf := pgf.FileSet.AddFile(doc.Path, -1, len(doc.Content))
-	// target := tokedf.Pos(offset)
-
 	slog.Info("hover", "offset", offset)
-	for _, spec := range pgf.File.Imports {
-		slog.Info("hover", "spec", spec.Path.Value, "pos", spec.Path.Pos(), "end", spec.Path.End())
-		if int(spec.Path.Pos()) <= offset && offset <= int(spec.Path.End()) {
-			// TODO: handle hover for imports
+	c, err := h.newCursor(doc, offset)
+	if err != nil {
+		slog.Error("hover", "err", err)
+		return reply(ctx, nil, nil)
+	}
+	tf := c.chk.Fset.File(c.file.Pos())
+	for _, spec := range c.file.Imports {
+		if spec.Path.Pos() <= c.pos && c.pos <= spec.Path.End() {
 			slog.Info("hover", "import", spec.Path.Value)
-			return reply(ctx, nil, nil)
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				return reply(ctx, nil, nil)
+			}
+			return reply(ctx, protocol.Hover{
+				Contents: protocol.MarkupContent{
+					Kind:  protocol.Markdown,
+					Value: c.importDoc(path),
+				},
+				Range: &protocol.Range{
+					Start: doc.OffsetToPosition(tf.Offset(spec.Path.Pos())),
+					End:   doc.OffsetToPosition(tf.Offset(spec.Path.End())),
+				},
+			}, nil)
 		}
 	}
 
-	token, err := doc.TokenAt(params.Position)
-	if err != nil {
-		return replyErr(ctx, reply, err)
+	id := c.identAt()
+	if id == nil {
+		return reply(ctx, nil, nil)
 	}
-	text := strings.TrimSpace(token.Text)
-
-	// FIXME: Use the AST package to do this + get type of token.
-	//
-	// This is just a quick PoC to get something working.
-
-	// strings.Split(p.Body,
-	text = strings.Split(text, "(")[0]
+	sig, symDoc, ok := c.hoverContent(id)
+	if !ok {
+		return reply(ctx, nil, nil)
+	}
+	return reply(ctx, protocol.Hover{
+		Contents: protocol.MarkupContent{
+			Kind:  protocol.Markdown,
+			Value: fmt.Sprintf("```gno\n%s\n```\n\n%s", sig, symDoc),
+		},
+		Range: &protocol.Range{
+			Start: doc.OffsetToPosition(tf.Offset(id.Pos())),
+			End:   doc.OffsetToPosition(tf.Offset(id.End())),
+		},
+	}, nil)
+}
 
-	text = strings.TrimSuffix(text, ",")
-	text = strings.TrimSuffix(text, ")")
+// identAt returns the identifier at the cursor, if any.
+func (c *cursor) identAt() *ast.Ident {
+	path, _ := astutil.PathEnclosingInterval(c.file, c.pos, c.pos)
+	if len(path) == 0 {
+		return nil
+	}
+	id, _ := path[0].(*ast.Ident)
+	return id
+}
 
-	// *mux.Request
-	text = strings.TrimPrefix(text, "*")
+// hoverContent returns the declaration and the documentation of the object
+// denoted by id. If the object is unknown, id is looked up in the stdlib
+// index.
+func (c *cursor) hoverContent(id *ast.Ident) (sig, doc string, ok bool) {
+	obj := c.pkg.Info.ObjectOf(id)
+	if obj == nil {
+		return c.hoverFromIndex(id)
+	}
+	q := types.RelativeTo(c.pkg.Types)
+	if b, ok := builtins[obj.Name()]; ok && obj.Parent() == types.Universe {
+		return b.sig, b.doc, true
+	}
+	sym := c.chk.DeclSymbol(obj)
+	if sym != nil {
+		doc = sym.Doc
+	}
+	switch obj := obj.(type) {
+	case *types.PkgName:
+		sig = fmt.Sprintf("package %s (%q)", obj.Imported().Name(), obj.Imported().Path())
+		if pkg := c.h.lookupIndexedPkg(obj.Imported().Path()); pkg != nil {
+			doc = pkg.Doc
+		}
+	case *types.Func:
+		sig = types.ObjectString(obj, q)
+		if sym != nil {
+			// the declaration is more readable, with the parameter names
+			sig = sym.Signature
+		}
+	case *types.TypeName:
+		sig = types.ObjectString(obj, q)
+		for _, n := range c.chk.DeclPath(obj) {
+			if spec, ok := n.(*ast.TypeSpec); ok {
+				sig = "type " + c.chk.Text(spec)
+				break
+			}
+		}
+	case *types.Const:
+		sig = types.ObjectString(obj, q) + " = " + obj.Val().ExactString()
+	default:
+		sig = types.ObjectString(obj, q)
+	}
+	return sig, strings.TrimSpace(doc), true
+}
 
-	slog.Info("hover", "pkg", len(stdlib.Packages))
+// hoverFromIndex returns the declaration and the documentation of id, the
+// selector of a package not loaded from its sources, from the stdlib index.
+func (c *cursor) hoverFromIndex(id *ast.Ident) (sig, doc string, ok bool) {
+	path, _ := astutil.PathEnclosingInterval(c.file, id.Pos(), id.End())
+	if len(path) < 2 {
+		return "", "", false
+	}
+	sel, ok := path[1].(*ast.SelectorExpr)
+	if !ok || sel.Sel != id {
+		return "", "", false
+	}
+	x, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return "", "", false
+	}
+	pkg := c.pkgOf(x)
+	if pkg == nil {
+		return "", "", false
+	}
+	for _, sym := range pkg.Symbols {
+		if sym.Name == id.Name {
+			slog.Info("hover", "pkg", pkg.ImportPath, "sym", sym.Name)
+			return sym.Signature, strings.TrimSpace(sym.Doc), true
+		}
+	}
+	return "", "", false
+}
 
-	parts := strings.Split(text, ".")
-	if len(parts) == 2 {
-		pkg := parts[0]
-		sym := parts[1]
+// maxSummary is the maximum number of exported symbols listed in the hover
+// of an import path.
+const maxSummary = 30
 
-		slog.Info("hover", "pkg", pkg, "sym", sym)
-		found := lookupSymbol(pkg, sym)
-		if found == nil && pgf.File != nil {
-			found = lookupSymbolByImports(sym, pgf.File.Imports)
+// importDoc returns the markdown documentation of the package imported with
+// importPath: its doc comment, the summary of its exported API, whether it's a
+// realm and a link to its documentation.
+func (c *cursor) importDoc(importPath string) string {
+	var (
+		name    = path.Base(importPath)
+		doc     string
+		summary []string
+	)
+	if pkg := c.h.lookupIndexedPkg(importPath); pkg != nil {
+		name, doc = pkg.Name, pkg.Doc
+		for _, sym := range pkg.Symbols {
+			if token.IsExported(sym.Name) {
+				summary = append(summary, symbolSummary(sym))
+			}
+		}
+	} else if pkg, err := c.chk.Import(importPath); err == nil {
+		name = pkg.Name()
+		q := types.RelativeTo(pkg)
+		for _, n := range pkg.Scope().Names() {
+			if obj := pkg.Scope().Lookup(n); obj.Exported() {
+				summary = append(summary, firstLine(types.ObjectString(obj, q)))
+			}
 		}
+	}
+	if len(summary) > maxSummary {
+		summary = append(summary[:maxSummary], fmt.Sprintf("// and %d more", len(summary)-maxSummary))
+	}
 
-		if found != nil {
-			return reply(ctx, protocol.Hover{
-				Contents: protocol.MarkupContent{
-					Kind:  protocol.Markdown,
-					Value: fmt.Sprintf("```go\n%s\n```\n\n%s", found.Signature, found.Doc),
-				},
-				Range: posToRange(
-					int(params.Position.Line),
-					[]int{token.Start, token.End},
-				),
-			}, nil)
+	parts := []string{fmt.Sprintf("```gno\npackage %s // import %q\n```", name, importPath)}
+	if doc = strings.TrimSpace(doc); doc != "" {
+		parts = append(parts, doc)
+	}
+	var mod *modfile.File
+	if dir, ok := c.h.importResolver(filepath.Dir(c.doc.Path))(importPath); ok {
+		mod, _, _ = c.h.findModFile(dir)
+	}
+	if strings.HasPrefix(importPath, "gno.land/r/") {
+		realm := "Realm"
+		if mod != nil {
+			realm += fmt.Sprintf(", module `%s`", mod.Module.Mod.Path)
 		}
+		parts = append(parts, realm)
+	}
+	if mod != nil && gno.IsDraft(mod) {
+		parts = append(parts, "Draft module")
+	}
+	if len(summary) > 0 {
+		parts = append(parts, "```gno\n"+strings.Join(summary, "\n")+"\n```")
 	}
+	parts = append(parts, fmt.Sprintf("[Documentation](%s)", c.h.docLink(importPath)))
+	return strings.Join(parts, "\n\n")
+}
+
+// docLink returns the URL of the documentation of the package imported with
+// path: its gno.land page for the examples, its sources otherwise.
+func (h *handler) docLink(path string) string {
+	if strings.HasPrefix(path, "gno.land/") {
+		return "https://" + path
+	}
+	link, _ := h.pkgLink(path)
+	return link
+}
+
+// symbolSummary returns the one line declaration of sym, a symbol of the
+// index.
+func symbolSummary(sym gno.Symbol) string {
+	sig := firstLine(sym.Signature)
+	switch sym.Kind {
+	case "func", "method":
+		return sig
+	case "var", "const":
+		return sym.Kind + " " + sig
+	}
+	return "type " + sig
+}
+
+// firstLine returns the first line of s.
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return strings.TrimSpace(line)
+}
+
+// builtin is the documentation of a predeclared identifier.
+type builtin struct {
+	sig string
+	doc string
+}
 
-	return reply(ctx, nil, nil)
+// builtins contains the documentation of the predeclared functions and
+// values, whose declaration isn't available.
+var builtins = map[string]builtin{
+	"append":  {"func append(slice []Type, elems ...Type) []Type", "The append built-in function appends elements to the end of a slice."},
+	"cap":     {"func cap(v Type) int", "The cap built-in function returns the capacity of v, according to its type."},
+	"clear":   {"func clear[T ~[]Type | ~map[Type]Type1](t T)", "The clear built-in function clears maps and slices."},
+	"close":   {"func close(c chan<- Type)", "The close built-in function closes a channel, which must be either bidirectional or send-only."},
+	"complex": {"func complex(r, i FloatType) ComplexType", "The complex built-in function constructs a complex value from two floating-point values."},
+	"copy":    {"func copy(dst, src []Type) int", "The copy built-in function copies elements from a source slice into a destination slice."},
+	"delete":  {"func delete(m map[Type]Type1, key Type)", "The delete built-in function deletes the element with the specified key (m[key]) from the map."},
+	"imag":    {"func imag(c ComplexType) FloatType", "The imag built-in function returns the imaginary part of the complex number c."},
+	"len":     {"func len(v Type) int", "The len built-in function returns the length of v, according to its type."},
+	"make":    {"func make(t Type, size ...IntegerType) Type", "The make built-in function allocates and initializes an object of type slice, map, or chan (only)."},
+	"max":     {"func max[T cmp.Ordered](x T, y ...T) T", "The max built-in function returns the largest value of a fixed number of arguments of cmp.Ordered types."},
+	"min":     {"func min[T cmp.Ordered](x T, y ...T) T", "The min built-in function returns the smallest value of a fixed number of arguments of cmp.Ordered types."},
+	"new":     {"func new(Type) *Type", "The new built-in function allocates memory. The value returned is a pointer to a newly allocated zero value of that type."},
+	"panic":   {"func panic(v any)", "The panic built-in function stops normal execution of the current goroutine."},
+	"print":   {"func print(args ...Type)", "The print built-in function formats its arguments in an implementation-specific way and writes the result to standard error."},
+	"println": {"func println(args ...Type)", "The println built-in function formats its arguments in an implementation-specific way and writes the result to standard error. Spaces are always added between arguments and a newline is appended."},
+	"real":    {"func real(c ComplexType) FloatType", "The real built-in function returns the real part of the complex number c."},
+	"recover": {"func recover() any", "The recover built-in function allows a program to manage behavior of a panicking goroutine."},
+	"nil":     {"var nil Type", "nil is a predeclared identifier representing the zero value for a pointer, channel, func, interface, map, or slice type."},
+	"iota":    {"const iota = 0", "iota is a predeclared identifier representing the untyped integer ordinal number of the current const specification in a (usually parenthesized) const declaration. It is zero-indexed."},
 }
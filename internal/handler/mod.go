@@ -0,0 +1,250 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"go.lsp.dev/protocol"
+	"golang.org/x/mod/modfile"
+
+	"github.com/jdkato/gnols/internal/gno"
+	"github.com/jdkato/gnols/internal/store"
+)
+
+// modDiagnostics returns the diagnostics of doc, a gno.mod file: the syntax
+// errors, the required modules which can't be found and the replacement
+// directories which don't exist.
+func (h *handler) modDiagnostics(doc *store.Document) []protocol.Diagnostic {
+	diagnostics := []protocol.Diagnostic{}
+	add := func(rng protocol.Range, severity protocol.DiagnosticSeverity, msg string) {
+		diagnostics = append(diagnostics, protocol.Diagnostic{
+			Range:    rng,
+			Severity: severity,
+			Source:   "gnols",
+			Message:  msg,
+		})
+	}
+	for _, err := range doc.Mod.Errors {
+		add(lineRange(doc, err.Pos.Line), protocol.DiagnosticSeverityError, err.Err.Error())
+	}
+	mod := doc.Mod.File
+	if mod == nil {
+		return diagnostics
+	}
+	if mod.Module == nil {
+		add(lineRange(doc, 1), protocol.DiagnosticSeverityError, "missing module directive")
+	}
+	var (
+		dir     = filepath.Dir(doc.Path)
+		resolve = h.importResolver(dir)
+	)
+	for _, req := range mod.Require {
+		if h.lookupIndexedPkg(req.Mod.Path) != nil {
+			continue
+		}
+		if _, ok := resolve(req.Mod.Path); !ok {
+			add(syntaxRange(doc, req.Syntax), protocol.DiagnosticSeverityWarning,
+				fmt.Sprintf("unknown module %s: not found in the workspace, the examples or the module cache", req.Mod.Path))
+		}
+	}
+	for _, rep := range mod.Replace {
+		if rep.New.Version != "" || !modfile.IsDirectoryPath(rep.New.Path) {
+			continue
+		}
+		newDir := rep.New.Path
+		if !filepath.IsAbs(newDir) {
+			newDir = filepath.Join(dir, newDir)
+		}
+		if fi, err := os.Stat(newDir); err != nil || !fi.IsDir() {
+			add(syntaxRange(doc, rep.Syntax), protocol.DiagnosticSeverityError,
+				fmt.Sprintf("replacement directory %s does not exist", rep.New.Path))
+		}
+	}
+	return diagnostics
+}
+
+// lineRange returns the range of the line n of doc, starting at 1.
+func lineRange(doc *store.Document, n int) protocol.Range {
+	line := max(n-1, 0)
+	var end int
+	if line < len(doc.Lines) {
+		end = len(strings.TrimRight(doc.Lines[line], "\r\n"))
+	}
+	return protocol.Range{
+		Start: protocol.Position{Line: uint32(line)},
+		End:   protocol.Position{Line: uint32(line), Character: uint32(end)},
+	}
+}
+
+// syntaxRange returns the range of line, a directive of doc.
+func syntaxRange(doc *store.Document, line *modfile.Line) protocol.Range {
+	return protocol.Range{
+		Start: doc.OffsetToPosition(line.Start.Byte),
+		End:   doc.OffsetToPosition(line.End.Byte),
+	}
+}
+
+// modCompletionItems returns the items of the module paths which can be
+// required or replaced at pos in doc, a gno.mod file. The modules already
+// required are skipped.
+func (h *handler) modCompletionItems(doc *store.Document, pos protocol.Position) []protocol.CompletionItem {
+	items := []protocol.CompletionItem{}
+	if int(pos.Line) >= len(doc.Lines) {
+		return items
+	}
+	var (
+		line      = doc.Lines[pos.Line]
+		lineStart = doc.PositionToOffset(protocol.Position{Line: pos.Line})
+		offset    = min(doc.PositionToOffset(pos)-lineStart, len(line))
+		start     = strings.LastIndexFunc(line[:offset], unicode.IsSpace) + 1
+		end       = offset + strings.IndexFunc(line[offset:]+"\n", unicode.IsSpace)
+	)
+	if !modPathExpected(doc, int(pos.Line), line[:start]) {
+		return items
+	}
+	var (
+		prefix = line[start:offset]
+		rng    = protocol.Range{
+			Start: doc.OffsetToPosition(lineStart + start),
+			End:   doc.OffsetToPosition(lineStart + end),
+		}
+		seen = make(map[string]bool)
+		c    = &cursor{h: h}
+	)
+	if mod := doc.Mod.File; mod != nil {
+		if mod.Module != nil {
+			seen[mod.Module.Mod.Path] = true
+		}
+		for _, req := range mod.Require {
+			if req.Syntax.Start.Line != int(pos.Line)+1 {
+				seen[req.Mod.Path] = true
+			}
+		}
+	}
+	h.completed = make(map[string]completedSymbol)
+	for _, pkgs := range [][]gno.Package{h.workspacePkgs(), h.stdlibPkgs()} {
+		for _, pkg := range pkgs {
+			if seen[pkg.ImportPath] || !strings.HasPrefix(pkg.ImportPath, "gno.land/") ||
+				!strings.HasPrefix(pkg.ImportPath, prefix) {
+				continue
+			}
+			seen[pkg.ImportPath] = true
+			c.rememberPkg(pkg.ImportPath, pkg.Name, pkg.Doc)
+			detail := "package " + pkg.Name
+			if pkg.Draft {
+				detail += " (draft)"
+			}
+			items = append(items, protocol.CompletionItem{
+				Label:  pkg.ImportPath,
+				Kind:   protocol.CompletionItemKindModule,
+				Detail: detail,
+				Data:   completionData{ID: pkg.ImportPath},
+				TextEdit: &protocol.TextEdit{
+					Range:   rng,
+					NewText: pkg.ImportPath,
+				},
+			})
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Label < items[j].Label })
+	return items
+}
+
+// modPathExpected returns true if a module path is expected after before, the
+// beginning of the line n of doc: the first argument of a require or replace
+// directive, or the replacement of a replace directive.
+func modPathExpected(doc *store.Document, n int, before string) bool {
+	fields := strings.Fields(before)
+	switch {
+	case len(fields) == 1 && (fields[0] == "require" || fields[0] == "replace"):
+		return true
+	case len(fields) >= 2 && fields[len(fields)-1] == "=>":
+		return fields[0] == "replace" || modBlock(doc, n) == "replace"
+	case len(fields) == 0:
+		return modBlock(doc, n) != ""
+	}
+	return false
+}
+
+// modBlock returns the verb of the require or replace block containing the
+// line n of doc, or an empty string.
+func modBlock(doc *store.Document, n int) string {
+	var block string
+	for _, line := range doc.Lines[:n] {
+		fields := strings.Fields(strings.ReplaceAll(line, "(", " ( "))
+		switch {
+		case len(fields) >= 2 && fields[1] == "(":
+			block = fields[0]
+		case len(fields) > 0 && fields[0] == ")":
+			block = ""
+		}
+	}
+	if block != "require" && block != "replace" {
+		return ""
+	}
+	return block
+}
+
+// modHover returns the documentation of the module path at pos in doc, a
+// gno.mod file, if it's a required or replaced module.
+func (h *handler) modHover(doc *store.Document, pos protocol.Position) *protocol.Hover {
+	mod := doc.Mod.File
+	if mod == nil || int(pos.Line) >= len(doc.Lines) {
+		return nil
+	}
+	var paths []string
+	for _, req := range mod.Require {
+		if req.Syntax.Start.Line == int(pos.Line)+1 {
+			paths = append(paths, req.Mod.Path)
+		}
+	}
+	for _, rep := range mod.Replace {
+		if rep.Syntax.Start.Line == int(pos.Line)+1 {
+			paths = append(paths, rep.Old.Path)
+			if rep.New.Version != "" || !modfile.IsDirectoryPath(rep.New.Path) {
+				paths = append(paths, rep.New.Path)
+			}
+		}
+	}
+	var (
+		line      = doc.Lines[pos.Line]
+		lineStart = doc.PositionToOffset(protocol.Position{Line: pos.Line})
+		offset    = doc.PositionToOffset(pos) - lineStart
+	)
+	for _, path := range paths {
+		start := strings.Index(line, path)
+		if start < 0 || offset < start || offset > start+len(path) {
+			continue
+		}
+		c := &cursor{
+			h:   h,
+			chk: gno.NewChecker(h.importResolver(filepath.Dir(doc.Path)), nil),
+			doc: doc,
+		}
+		return &protocol.Hover{
+			Contents: protocol.MarkupContent{
+				Kind:  protocol.Markdown,
+				Value: c.importDoc(path),
+			},
+			Range: &protocol.Range{
+				Start: doc.OffsetToPosition(lineStart + start),
+				End:   doc.OffsetToPosition(lineStart + start + len(path)),
+			},
+		}
+	}
+	return nil
+}
+
+// formatMod returns the formatted content of doc, a gno.mod file.
+func formatMod(doc *store.Document) ([]byte, error) {
+	if len(doc.Mod.Errors) > 0 || doc.Mod.File == nil {
+		return nil, errors.New("gno.mod contains errors")
+	}
+	doc.Mod.File.Cleanup()
+	return modfile.Format(doc.Mod.File.Syntax), nil
+}
@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"go.lsp.dev/protocol"
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// postfixItems returns the postfix completions of sel, whose X is a value of
+// type t: the items replace the whole selector with a statement using X, like
+// "x.nil" which becomes "if x == nil {}". They are offered only if sel is a
+// statement.
+func (c *cursor) postfixItems(sel *ast.SelectorExpr, prefix string, t types.Type) []protocol.CompletionItem {
+	if !c.isStmt(sel, prefix) {
+		return nil
+	}
+	var (
+		tf    = c.chk.Fset.File(c.file.Pos())
+		start = tf.Offset(sel.X.Pos())
+		x     = c.doc.Content[start:tf.Offset(sel.X.End())]
+		ex    = snippetEscape(x)
+		rng   = protocol.Range{
+			Start: c.doc.OffsetToPosition(start),
+			End:   c.doc.OffsetToPosition(c.offset),
+		}
+		items []protocol.CompletionItem
+		add   = func(label, detail, body string) {
+			if !strings.HasPrefix(label, prefix) {
+				return
+			}
+			items = append(items, protocol.CompletionItem{
+				Label:            label,
+				Kind:             protocol.CompletionItemKindSnippet,
+				Detail:           detail,
+				FilterText:       x + "." + label,
+				InsertTextFormat: protocol.InsertTextFormatSnippet,
+				TextEdit: &protocol.TextEdit{
+					Range:   rng,
+					NewText: body,
+				},
+			})
+		}
+	)
+	if _, ok := t.(*types.TypeParam); ok {
+		// the underlying type is the constraint, which tells nothing about
+		// the operations
+		return nil
+	}
+	u := t.Underlying()
+	if b, ok := u.(*types.Basic); ok && b.Info()&types.IsBoolean != 0 {
+		add("if", "if "+x+" {}", fmt.Sprintf("if %s {\n\t$0\n}", ex))
+	}
+	if nillable(u) {
+		add("nil", "if "+x+" == nil {}", fmt.Sprintf("if %s == nil {\n\t$0\n}", ex))
+		add("notnil", "if "+x+" != nil {}", fmt.Sprintf("if %s != nil {\n\t$0\n}", ex))
+	}
+	switch u := u.(type) {
+	case *types.Slice, *types.Array:
+		add("for", "for i, v := range "+x+" {}", fmt.Sprintf("for ${1:i}, ${2:v} := range %s {\n\t$0\n}", ex))
+	case *types.Pointer:
+		if _, ok := u.Elem().Underlying().(*types.Array); ok {
+			add("for", "for i, v := range "+x+" {}", fmt.Sprintf("for ${1:i}, ${2:v} := range %s {\n\t$0\n}", ex))
+		}
+	case *types.Basic:
+		if u.Info()&types.IsString != 0 {
+			add("for", "for i, r := range "+x+" {}", fmt.Sprintf("for ${1:i}, ${2:r} := range %s {\n\t$0\n}", ex))
+		}
+	case *types.Map:
+		add("for", "for k, v := range "+x+" {}", fmt.Sprintf("for ${1:k}, ${2:v} := range %s {\n\t$0\n}", ex))
+	case *types.Chan:
+		add("for", "for v := range "+x+" {}", fmt.Sprintf("for ${1:v} := range %s {\n\t$0\n}", ex))
+	}
+	if types.Identical(t, errorType) {
+		if zeros, ok := c.errorReturn(); ok {
+			ret := strings.Join(append(zeros, ex), ", ")
+			add("return", "if "+x+" != nil { return "+x+" }", fmt.Sprintf("if %s != nil {\n\treturn %s\n}", ex, ret))
+		}
+	}
+	return items
+}
+
+var errorType = types.Universe.Lookup("error").Type()
+
+// isStmt returns true if sel is an expression statement, which is the case
+// for an incomplete selector followed by the next statement.
+func (c *cursor) isStmt(sel *ast.SelectorExpr, prefix string) bool {
+	path, _ := astutil.PathEnclosingInterval(c.file, sel.Pos(), sel.End())
+	if len(path) < 2 {
+		return false
+	}
+	switch n := path[1].(type) {
+	case *ast.ExprStmt:
+		return true
+	case *ast.CallExpr:
+		// the parser has taken "x.\nfoo()" as the call "x.foo()"
+		if len(path) < 3 || n.Fun != sel || prefix != "" {
+			return false
+		}
+		_, ok := path[2].(*ast.ExprStmt)
+		return ok
+	}
+	return false
+}
+
+// errorReturn returns the zero values of the results of the function
+// enclosing the cursor, except the last one which is an error. ok is false if
+// the function doesn't return an error last.
+func (c *cursor) errorReturn() (zeros []string, ok bool) {
+	sig := c.enclosingSignature()
+	if sig == nil || sig.Results().Len() == 0 {
+		return nil, false
+	}
+	res := sig.Results()
+	if !types.Identical(res.At(res.Len()-1).Type(), errorType) {
+		return nil, false
+	}
+	q := types.RelativeTo(c.pkg.Types)
+	for i := 0; i < res.Len()-1; i++ {
+		zeros = append(zeros, zeroValue(res.At(i).Type(), q))
+	}
+	return zeros, true
+}
+
+// enclosingSignature returns the signature of the innermost function
+// enclosing the cursor.
+func (c *cursor) enclosingSignature() *types.Signature {
+	path, _ := astutil.PathEnclosingInterval(c.file, c.pos, c.pos)
+	for _, n := range path {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			if sig, ok := c.pkg.Info.TypeOf(n).(*types.Signature); ok {
+				return sig
+			}
+			return nil
+		case *ast.FuncDecl:
+			if fn, ok := c.pkg.Info.Defs[n.Name].(*types.Func); ok {
+				return fn.Type().(*types.Signature)
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// nillable returns true if nil is a valid value of a type whose underlying
+// type is u.
+func nillable(u types.Type) bool {
+	switch u.(type) {
+	case *types.Pointer, *types.Slice, *types.Map, *types.Chan, *types.Signature, *types.Interface:
+		return true
+	}
+	return false
+}
+
+// snippetEscape escapes the characters of s which have a meaning in the
+// snippet syntax.
+func snippetEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "$", `\$`, "}", `\}`).Replace(s)
+}
@@ -14,7 +14,11 @@ func (h *handler) handleTextDocumentPrepareRename(ctx context.Context, reply jso
 		return replyErr(ctx, reply, err)
 	}
 
-	err := h.getBinManager().PrepareRename(ctx,
+	bm, err := h.getBinManager(params.TextDocument.URI.Filename())
+	if err != nil {
+		return replyErr(ctx, reply, err)
+	}
+	err = bm.PrepareRename(ctx,
 		params.TextDocument.URI, params.Position.Line, params.Position.Character,
 	)
 	if err != nil {
@@ -33,7 +37,11 @@ func (h *handler) handleTextDocumentRename(ctx context.Context, reply jsonrpc2.R
 		return replyErr(ctx, reply, err)
 	}
 
-	docEdits, err := h.getBinManager().Rename(ctx,
+	bm, err := h.getBinManager(params.TextDocument.URI.Filename())
+	if err != nil {
+		return replyErr(ctx, reply, err)
+	}
+	docEdits, err := bm.Rename(ctx,
 		params.TextDocument.URI, params.Position.Line, params.Position.Character,
 		params.NewName,
 	)
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"go/ast"
+	"path/filepath"
+	"strings"
+
+	"go.lsp.dev/protocol"
+	"golang.org/x/tools/go/ast/astutil"
+
+	"github.com/jdkato/gnols/internal/gno"
+)
+
+// snippet is a completion item inserting a template of common Gno code.
+type snippet struct {
+	label  string
+	detail string
+	body   string
+	// imports contains the packages used by the snippet.
+	imports []string
+	// stmt is true if the snippet is a statement, false if it's a top-level
+	// declaration.
+	stmt bool
+	// when returns true if the snippet is relevant in the file described by
+	// the snippetContext.
+	when func(snippetContext) bool
+}
+
+// snippetContext describes the file where snippets are offered.
+type snippetContext struct {
+	test     bool
+	filetest bool
+	realm    bool
+}
+
+var snippets = []snippet{
+	{
+		label:  "Render",
+		detail: "func Render(path string) string",
+		body:   "// Render returns the content of the realm for path.\nfunc Render(path string) string {\n\t${1:return \"\"}\n}",
+		when:   func(c snippetContext) bool { return c.realm && !c.test && !c.filetest },
+	},
+	{
+		label:  "init",
+		detail: "init with owner check",
+		body: "var owner std.Address\n\nfunc init() {\n\towner = std.GetOrigCaller()\n}\n\n" +
+			"func assertIsOwner() {\n\tif std.PrevRealm().Addr() != owner {\n\t\tpanic(\"${1:caller is not the owner}\")\n\t}\n}",
+		imports: []string{"std"},
+		when:    func(c snippetContext) bool { return c.realm && !c.test && !c.filetest },
+	},
+	{
+		label:   "prevrealm",
+		detail:  "caller authorization with std.PrevRealm()",
+		body:    "if std.PrevRealm().Addr() != ${1:owner} {\n\tpanic(\"${2:unauthorized}\")\n}",
+		imports: []string{"std"},
+		stmt:    true,
+		when:    func(c snippetContext) bool { return c.realm },
+	},
+	{
+		label:   "origcaller",
+		detail:  "caller authorization with std.GetOrigCaller()",
+		body:    "if std.GetOrigCaller() != ${1:owner} {\n\tpanic(\"${2:unauthorized}\")\n}",
+		imports: []string{"std"},
+		stmt:    true,
+		when:    func(c snippetContext) bool { return c.realm },
+	},
+	{
+		label:   "Test",
+		detail:  "func TestX(t *testing.T)",
+		body:    "func Test${1:Name}(t *testing.T) {\n\t$0\n}",
+		imports: []string{"testing"},
+		when:    func(c snippetContext) bool { return c.test },
+	},
+	{
+		label:  "main",
+		detail: "filetest with expected output",
+		body:   "func main() {\n\t${1:println(\"hello\")}\n}\n\n// Output:\n// ${2:hello}",
+		when:   func(c snippetContext) bool { return c.filetest },
+	},
+}
+
+// snippetItems returns the items of the snippets relevant at the cursor and
+// matching prefix.
+func (c *cursor) snippetItems(prefix string) []protocol.CompletionItem {
+	var (
+		items = []protocol.CompletionItem{}
+		ctx   = snippetContext{
+			test:     strings.HasSuffix(c.doc.Path, "_test.gno"),
+			filetest: strings.HasSuffix(c.doc.Path, "_filetest.gno"),
+			realm:    isRealm(filepath.Dir(c.doc.Path)),
+		}
+		stmt = c.inFuncBody()
+	)
+	for _, s := range snippets {
+		if s.stmt != stmt || !s.when(ctx) || !strings.HasPrefix(strings.ToLower(s.label), strings.ToLower(prefix)) {
+			continue
+		}
+		item := protocol.CompletionItem{
+			Label:            s.label,
+			Kind:             protocol.CompletionItemKindSnippet,
+			Detail:           s.detail,
+			InsertText:       s.body,
+			InsertTextFormat: protocol.InsertTextFormatSnippet,
+			SortText:         "97" + s.label,
+		}
+		for _, path := range s.imports {
+			if !c.imports(path) {
+				item.AdditionalTextEdits = append(item.AdditionalTextEdits, c.importEdit(path))
+			}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// inFuncBody returns true if the cursor is inside a function body.
+func (c *cursor) inFuncBody() bool {
+	path, _ := astutil.PathEnclosingInterval(c.file, c.pos, c.pos)
+	for _, n := range path {
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			return n.Body != nil && n.Body.Lbrace < c.pos && c.pos <= n.Body.Rbrace
+		case *ast.FuncLit:
+			return n.Body.Lbrace < c.pos && c.pos <= n.Body.Rbrace
+		}
+	}
+	return false
+}
+
+// isRealm returns true if dir contains a realm, i.e. a package whose path
+// starts with gno.land/r/.
+func isRealm(dir string) bool {
+	if pkgPath, ok := gno.PkgPath(dir); ok {
+		return strings.HasPrefix(pkgPath, "gno.land/r/")
+	}
+	// without gno.mod, rely on the directory layout of the examples
+	return strings.Contains(filepath.ToSlash(dir)+"/", "/gno.land/r/")
+}
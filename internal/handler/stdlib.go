@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/jdkato/gnols/internal/gno"
+	"github.com/jdkato/gnols/internal/stdlib"
+)
+
+// rootDirs are the directories of the gno repository containing the indexed
+// packages, like in cmd/gen.
+var rootDirs = []string{"gnovm/stdlibs", "examples"}
+
+// stdlibIndex is the index of the standard libraries and the examples. It's
+// the index of the gno repository configured with the root setting, built in
+// the background, or the embedded index matching the version of the gno
+// binary.
+type stdlibIndex struct {
+	mu sync.RWMutex
+	// root is the gno repository being indexed.
+	root string
+	// rootPkgs contains the packages of root, nil until they are indexed.
+	rootPkgs []gno.Package
+	// version is the version of the gno binary.
+	version string
+	// versionPkgs contains the packages of the embedded index of version, nil
+	// if there's none.
+	versionPkgs []gno.Package
+}
+
+// stdlibPkgs returns the packages of the gno repository configured with the
+// root setting if they are indexed, or the packages of the embedded index
+// matching the gno version. If there's none, the index of the latest version
+// is returned.
+func (h *handler) stdlibPkgs() []gno.Package {
+	h.stdlibIndex.mu.RLock()
+	defer h.stdlibIndex.mu.RUnlock()
+	if h.stdlibIndex.rootPkgs != nil {
+		return h.stdlibIndex.rootPkgs
+	}
+	if h.stdlibIndex.versionPkgs != nil {
+		return h.stdlibIndex.versionPkgs
+	}
+	return stdlib.Packages
+}
+
+// loadVersionIndex selects the embedded index matching version, the version
+// of the gno binary. If there's none and no gno repository is configured, it
+// returns a warning: the index may not match the gno version.
+func (h *handler) loadVersionIndex(version string) (warning string) {
+	h.stdlibIndex.mu.Lock()
+	defer h.stdlibIndex.mu.Unlock()
+	if version == h.stdlibIndex.version {
+		return ""
+	}
+	pkgs, ok := stdlib.Lookup(version)
+	h.stdlibIndex.version, h.stdlibIndex.versionPkgs = version, pkgs
+	slog.Info("gno version", "version", version, "indexed", ok)
+	if ok || h.stdlibIndex.root != "" {
+		return ""
+	}
+	return fmt.Sprintf("No symbol index for gno %s, completion and hover may offer APIs which don't exist in this version. Set the root setting to index the gno repository.", version)
+}
+
+// loadRootIndex indexes the packages of root in the background, unless it's
+// already done. If root is empty, the embedded index is used.
+func (h *handler) loadRootIndex(root string) {
+	h.stdlibIndex.mu.Lock()
+	defer h.stdlibIndex.mu.Unlock()
+	if root == h.stdlibIndex.root {
+		return
+	}
+	h.stdlibIndex.root, h.stdlibIndex.rootPkgs = root, nil
+	if root == "" {
+		return
+	}
+	go func() {
+		start := time.Now()
+		pkgs, err := indexRoot(root, h.symbolCache)
+		if err != nil {
+			slog.Error("index gno root", "root", root, "err", err)
+			return
+		}
+		h.stdlibIndex.mu.Lock()
+		defer h.stdlibIndex.mu.Unlock()
+		if h.stdlibIndex.root != root {
+			// the setting changed in the meantime
+			return
+		}
+		h.stdlibIndex.rootPkgs = pkgs
+		slog.Info("gno root indexed", "root", root, "pkgs", len(pkgs), "duration", time.Since(start))
+	}()
+}
+
+// indexRoot returns the packages of the standard libraries and the examples of
+// the gno repository root.
+func indexRoot(root string, cache *gno.SymbolCache) ([]gno.Package, error) {
+	pkgs := []gno.Package{}
+	for _, dir := range rootDirs {
+		dir = filepath.Join(root, dir)
+		index := gno.NewIndex("", dir, cache)
+		if err := index.Load(); err != nil {
+			return nil, fmt.Errorf("indexRoot: %w", err)
+		}
+		pkgs = append(pkgs, index.Packages()...)
+	}
+	return pkgs, nil
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jdkato/gnols/internal/gno"
+	"github.com/jdkato/gnols/internal/stdlib"
+)
+
+func TestLoadRootIndex(t *testing.T) {
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		"gnovm/stdlibs/strings/strings.gno":         "package strings\n\nfunc Runtime() {}\n",
+		"examples/gno.land/p/demo/foo/foo.gno":      "package foo\n\nfunc Foo() {}\n",
+		"examples/gno.land/p/demo/foo/gno.mod":      "module gno.land/p/demo/foo\n",
+		"examples/gno.land/p/demo/foo/foo_test.gno": "package foo\n\nfunc TestFoo() {}\n",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := &handler{}
+	if got := h.stdlibPkgs(); len(got) != len(stdlib.Packages) {
+		t.Fatalf("Expected the embedded index without root, got %d packages", len(got))
+	}
+
+	h.loadRootIndex(root)
+	deadline := time.Now().Add(5 * time.Second)
+	for h.stdlibPkgs()[0].Dir == "" {
+		if time.Now().After(deadline) {
+			t.Fatal("root not indexed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	var paths []string
+	for _, pkg := range h.stdlibPkgs() {
+		paths = append(paths, pkg.ImportPath)
+	}
+	if want := []string{"strings", "gno.land/p/demo/foo"}; !slices.Equal(paths, want) {
+		t.Errorf("Expected packages %v, got %v", want, paths)
+	}
+
+	// back to the embedded index
+	h.loadRootIndex("")
+	if got := h.stdlibPkgs(); len(got) != len(stdlib.Packages) {
+		t.Errorf("Expected the embedded index without root, got %d packages", len(got))
+	}
+}
+
+func TestLoadVersionIndex(t *testing.T) {
+	defer func(v string) { stdlib.Version = v }(stdlib.Version)
+
+	// unknown version of the latest index
+	stdlib.Version = ""
+	h := &handler{}
+	if w := h.loadVersionIndex("v1"); !strings.Contains(w, "No symbol index for gno v1") {
+		t.Errorf("Expected a warning, got %q", w)
+	}
+
+	stdlib.Version = "v2"
+	h = &handler{}
+	if w := h.loadVersionIndex("v2"); w != "" {
+		t.Errorf("Expected no warning for the latest version, got %q", w)
+	}
+	if got := h.stdlibPkgs(); len(got) != len(stdlib.Packages) {
+		t.Errorf("Expected the latest index, got %d packages", len(got))
+	}
+	h = &handler{}
+	if w := h.loadVersionIndex("v1"); !strings.Contains(w, "No symbol index for gno v1") {
+		t.Errorf("Expected a warning, got %q", w)
+	}
+	if w := h.loadVersionIndex("v1"); w != "" {
+		t.Errorf("Expected a single warning, got %q", w)
+	}
+	h = &handler{stdlibIndex: stdlibIndex{root: "/src/gno"}}
+	if w := h.loadVersionIndex("v1"); w != "" {
+		t.Errorf("Expected no warning with a gno repository, got %q", w)
+	}
+}
+
+func TestLoadWorkspacesVersion(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires sh")
+	}
+	defer func(v string) { stdlib.Version = v }(stdlib.Version)
+	stdlib.Version = "v2"
+	dir := t.TempDir()
+	// newWorkspace returns a workspace whose gno binary runs script.
+	newWorkspace := func(name, script string) *workspace {
+		bin := filepath.Join(dir, name)
+		if err := os.WriteFile(bin, []byte("#!/bin/sh\n"+script+"\n"), 0o755); err != nil { //nolint:gosec
+			t.Fatal(err)
+		}
+		bm, err := gno.NewBinManager(dir, bin, "", "", "", false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &workspace{folder: dir, binManager: bm}
+	}
+	var (
+		broken = newWorkspace("broken", "exit 1")
+		v1     = newWorkspace("v1", "echo 'gno version: v1'")
+		v2     = newWorkspace("v2", "echo 'gno version: v2'")
+	)
+
+	// the version of the first folder can't be read
+	h := &handler{workspaces: []*workspace{broken, v2}}
+	if w := h.loadWorkspacesVersion(); w != "" {
+		t.Errorf("Expected no warning, got %q", w)
+	}
+	if h.stdlibIndex.version != "v2" {
+		t.Errorf("Expected the index of v2, got %q", h.stdlibIndex.version)
+	}
+
+	h = &handler{workspaces: []*workspace{v2, v1}}
+	if w := h.loadWorkspacesVersion(); !strings.Contains(w, "different gno versions (v2, v1)") {
+		t.Errorf("Expected a warning, got %q", w)
+	}
+}
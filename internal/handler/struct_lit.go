@@ -0,0 +1,321 @@
+package handler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"go.lsp.dev/protocol"
+	"golang.org/x/tools/go/ast/astutil"
+
+	"github.com/jdkato/gnols/internal/gno"
+)
+
+// litField is a field of the struct type of a composite literal.
+type litField struct {
+	id   string
+	sym  gno.Symbol
+	zero string
+	// link is the location of the field's source, empty if the field is
+	// resolved from the stdlib index.
+	link string
+}
+
+// structLitKeyAt returns the composite literal whose key is being typed at the
+// cursor, and the key typed so far.
+func (c *cursor) structLitKeyAt() (*ast.CompositeLit, string, bool) {
+	path, _ := astutil.PathEnclosingInterval(c.file, c.pos, c.pos)
+	if len(path) == 0 {
+		return nil, "", false
+	}
+	if lit, ok := path[0].(*ast.CompositeLit); ok {
+		// between the elements
+		return lit, "", lit.Lbrace < c.pos && c.pos <= lit.Rbrace
+	}
+	id, ok := path[0].(*ast.Ident)
+	if !ok || len(path) < 2 {
+		return nil, "", false
+	}
+	prefix := id.Name[:min(int(c.pos-id.Pos()), len(id.Name))]
+	switch n := path[1].(type) {
+	case *ast.CompositeLit:
+		// an element without key yet, not the type of the literal
+		return n, prefix, n.Lbrace < c.pos && c.pos <= n.Rbrace
+	case *ast.KeyValueExpr:
+		if len(path) < 3 || n.Key != id {
+			break
+		}
+		if lit, ok := path[2].(*ast.CompositeLit); ok {
+			return lit, prefix, true
+		}
+	}
+	return nil, "", false
+}
+
+// structLitItems returns the items of the fields of lit not set yet, or nil if
+// lit isn't a struct literal.
+func (c *cursor) structLitItems(lit *ast.CompositeLit, prefix string) []protocol.CompletionItem {
+	fields := c.structLitFields(lit)
+	if fields == nil {
+		return nil
+	}
+	set := keys(lit)
+	items := []protocol.CompletionItem{}
+	for _, f := range fields {
+		if set[f.sym.Name] || !strings.HasPrefix(f.sym.Name, prefix) {
+			continue
+		}
+		if f.link != "" {
+			c.remember(f.id, f.sym, f.link)
+		}
+		item := symbolItem(f.id, f.sym)
+		item.InsertText = f.sym.Name + ": "
+		items = append(items, item)
+	}
+	return items
+}
+
+// keys returns the keys of the elements of lit.
+func keys(lit *ast.CompositeLit) map[string]bool {
+	set := make(map[string]bool)
+	for _, elt := range lit.Elts {
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			if id, ok := kv.Key.(*ast.Ident); ok {
+				set[id.Name] = true
+			}
+		}
+	}
+	return set
+}
+
+// structLitFields returns the fields of the struct type of lit, or nil if lit
+// isn't a struct literal. The fields come from the type information, or from
+// the symbol indexes if the type is unknown.
+func (c *cursor) structLitFields(lit *ast.CompositeLit) []litField {
+	if tv, ok := c.pkg.Info.Types[lit]; ok && tv.Type != nil && tv.Type != types.Typ[types.Invalid] {
+		st, ok := tv.Type.Underlying().(*types.Struct)
+		if !ok {
+			return nil
+		}
+		var (
+			fields = []litField{}
+			q      = types.RelativeTo(c.pkg.Types)
+			owner  string
+		)
+		if named, ok := tv.Type.(*types.Named); ok {
+			owner = named.Obj().Name()
+		}
+		for i := 0; i < st.NumFields(); i++ {
+			f := st.Field(i)
+			if !f.Exported() && f.Pkg() != c.pkg.Types {
+				continue
+			}
+			fields = append(fields, litField{
+				id:   objectID(f, owner),
+				sym:  c.symbolOf(f),
+				zero: zeroValue(f.Type(), q),
+				link: c.objectLink(f),
+			})
+		}
+		return fields
+	}
+
+	// look up the type in the symbol indexes
+	var (
+		pkg      *gno.Package
+		typeName string
+		qual     string
+	)
+	switch t := lit.Type.(type) {
+	case *ast.Ident:
+		pkg, typeName = &c.ws.currentPkg, t.Name
+	case *ast.SelectorExpr:
+		id, ok := t.X.(*ast.Ident)
+		if !ok {
+			return nil
+		}
+		pkg = c.pkgOf(id)
+		typeName, qual = t.Sel.Name, id.Name
+	}
+	if pkg == nil {
+		return nil
+	}
+	for _, sym := range pkg.Symbols {
+		if sym.Name != typeName || sym.Kind != "struct" {
+			continue
+		}
+		var (
+			fields       = []litField{}
+			pkgLink, own = c.h.pkgLink(pkg.ImportPath)
+		)
+		for _, f := range sym.Fields {
+			if f.Kind != "field" || qual != "" && !token.IsExported(f.Name) {
+				// methods are listed with the fields
+				continue
+			}
+			// the signature of a field is the one of its name only, like
+			// "Y int" for "X, Y int"
+			typ, _, _ := strings.Cut(strings.TrimPrefix(f.Signature, f.Name), "`") // drop the tag
+			typ = strings.TrimSpace(typ)
+			var link string
+			if own {
+				link = symbolLink(pkgLink, f)
+			}
+			fields = append(fields, litField{
+				id:   pkg.SymbolID(f),
+				sym:  f,
+				zero: indexZeroValue(typ, pkg, qual),
+				link: link,
+			})
+		}
+		return fields
+	}
+	return nil
+}
+
+// zeroValue returns the expression of the zero value of t.
+func zeroValue(t types.Type, q types.Qualifier) string {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsNumeric != 0:
+			return "0"
+		case u.Info()&types.IsString != 0:
+			return `""`
+		case u.Info()&types.IsBoolean != 0:
+			return "false"
+		}
+	case *types.Struct, *types.Array:
+		return types.TypeString(t, q) + "{}"
+	}
+	return "nil"
+}
+
+// indexZeroValue returns the expression of the zero value of typ, a type
+// expression of a symbol of pkg. qual is the name used to refer to pkg, empty
+// for the current package.
+func indexZeroValue(typ string, pkg *gno.Package, qual string) string {
+	expr, err := parser.ParseExpr(typ)
+	if err != nil {
+		return "nil"
+	}
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if obj := types.Universe.Lookup(t.Name); obj != nil {
+			if tn, ok := obj.(*types.TypeName); ok {
+				return zeroValue(tn.Type(), nil)
+			}
+			return "nil"
+		}
+		for _, sym := range pkg.Symbols {
+			if sym.Name != t.Name {
+				continue
+			}
+			name := t.Name
+			if qual != "" {
+				name = qual + "." + name
+			}
+			switch sym.Kind {
+			case "struct", "array":
+				return name + "{}"
+			case "interface", "map", "chan":
+				return "nil"
+			}
+			if sym.Type != "" && sym.Type != t.Name {
+				// defined from an other type
+				return indexZeroValue(sym.Type, pkg, qual)
+			}
+		}
+	case *ast.SelectorExpr:
+		// type from an other package, assume a struct
+		return typ + "{}"
+	case *ast.ArrayType:
+		if t.Len != nil {
+			return typ + "{}"
+		}
+	}
+	return "nil"
+}
+
+// fillStruct returns the code action filling the struct literal at the cursor
+// with the zero values of its missing fields.
+func (c *cursor) fillStruct(uri protocol.DocumentURI) (protocol.CodeAction, bool) {
+	path, _ := astutil.PathEnclosingInterval(c.file, c.pos, c.pos)
+	var lit *ast.CompositeLit
+	for _, n := range path {
+		if l, ok := n.(*ast.CompositeLit); ok {
+			lit = l
+			break
+		}
+	}
+	if lit == nil || lit.Type == nil {
+		return protocol.CodeAction{}, false
+	}
+	for _, elt := range lit.Elts {
+		if _, ok := elt.(*ast.KeyValueExpr); !ok {
+			// fields are set by position
+			return protocol.CodeAction{}, false
+		}
+	}
+	var (
+		set     = keys(lit)
+		missing []string
+	)
+	for _, f := range c.structLitFields(lit) {
+		if !set[f.sym.Name] {
+			missing = append(missing, f.sym.Name+": "+f.zero)
+		}
+	}
+	if len(missing) == 0 {
+		return protocol.CodeAction{}, false
+	}
+
+	var (
+		tf     = c.chk.Fset.File(c.file.Pos())
+		start  = c.doc.OffsetToPosition(tf.Offset(lit.Lbrace) + 1)
+		end    = c.doc.OffsetToPosition(tf.Offset(lit.Rbrace))
+		indent = c.indentOf(int(start.Line))
+		edit   protocol.TextEdit
+	)
+	switch {
+	case len(lit.Elts) == 0:
+		edit = protocol.TextEdit{
+			Range:   protocol.Range{Start: start, End: end},
+			NewText: "\n" + indent + "\t" + strings.Join(missing, ",\n"+indent+"\t") + ",\n" + indent,
+		}
+	case start.Line == end.Line:
+		// single line literal
+		pos := c.doc.OffsetToPosition(tf.Offset(lit.Elts[len(lit.Elts)-1].End()))
+		edit = protocol.TextEdit{
+			Range:   protocol.Range{Start: pos, End: pos},
+			NewText: ", " + strings.Join(missing, ", "),
+		}
+	default:
+		pos := c.doc.OffsetToPosition(tf.Offset(lit.Elts[len(lit.Elts)-1].End()))
+		edit = protocol.TextEdit{
+			Range:   protocol.Range{Start: pos, End: pos},
+			NewText: ",\n" + indent + "\t" + strings.Join(missing, ",\n"+indent+"\t"),
+		}
+	}
+	return protocol.CodeAction{
+		Title: fmt.Sprintf("Fill %s", types.ExprString(lit.Type)),
+		Kind:  protocol.RefactorRewrite,
+		Edit: &protocol.WorkspaceEdit{
+			Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+				uri: {edit},
+			},
+		},
+	}, true
+}
+
+// indentOf returns the indentation of the line.
+func (c *cursor) indentOf(line int) string {
+	if line >= len(c.doc.Lines) {
+		return ""
+	}
+	l := c.doc.Lines[line]
+	return l[:len(l)-len(strings.TrimLeft(l, " \t"))]
+}
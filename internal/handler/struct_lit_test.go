@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"go/ast"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jdkato/gnols/internal/gno"
+)
+
+func TestStructLitFieldsIndex(t *testing.T) {
+	// P is only known by the index, so its fields come from its symbol.
+	indexDir := t.TempDir()
+	err := os.WriteFile(filepath.Join(indexDir, "p.gno"), []byte(`package foo
+
+type P struct {
+	X, Y       int
+	Name, Desc string `+"`json:\"name\"`"+`
+	Next       *P
+}
+`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkgs, err := gno.ParsePackages(indexDir, indexDir)
+	if err != nil || len(pkgs) != 1 {
+		t.Fatalf("ParsePackages: %v, %v", pkgs, err)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "x.gno")
+	if err := os.WriteFile(path, []byte("package foo\n\nvar _ = P{}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	chk := gno.NewChecker(func(string) (string, bool) { return "", false }, nil)
+	pkg, err := chk.Check("foo", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := chk.File(path)
+	var lit *ast.CompositeLit
+	ast.Inspect(file, func(n ast.Node) bool {
+		if l, ok := n.(*ast.CompositeLit); ok {
+			lit = l
+		}
+		return lit == nil
+	})
+	c := &cursor{
+		h:    &handler{},
+		ws:   &workspace{folder: dir, currentPkg: pkgs[0]},
+		chk:  chk,
+		pkg:  pkg,
+		file: file,
+	}
+
+	var got [][2]string
+	for _, f := range c.structLitFields(lit) {
+		got = append(got, [2]string{f.sym.Name, f.zero})
+	}
+	want := [][2]string{{"X", "0"}, {"Y", "0"}, {"Name", `""`}, {"Desc", `""`}, {"Next", "nil"}}
+	if len(got) != len(want) {
+		t.Fatalf("expected = %v, got = %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("expected = %v, got = %v", want, got)
+			break
+		}
+	}
+}
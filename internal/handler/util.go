@@ -3,14 +3,11 @@ package handler
 import (
 	"encoding/json"
 	"fmt"
-	"go/ast"
-	"strings"
 
 	"go.lsp.dev/jsonrpc2"
 	"go.lsp.dev/protocol"
 
 	"github.com/jdkato/gnols/internal/gno"
-	"github.com/jdkato/gnols/internal/stdlib"
 )
 
 func readParams(req jsonrpc2.Request, params any) error {
@@ -23,55 +20,16 @@ func readParams(req jsonrpc2.Request, params any) error {
 	return nil
 }
 
-func posToRange(line int, span []int) *protocol.Range {
-	return &protocol.Range{
-		Start: protocol.Position{
-			Line:      uint32(line - 1),
-			Character: uint32(span[0] - 1),
-		},
-		End: protocol.Position{
-			Line:      uint32(line - 1),
-			Character: uint32(span[1] - 1),
-		},
-	}
-}
-
-func lookupSymbol(pkg, symbol string) *gno.Symbol {
-	for _, p := range stdlib.Packages {
-		if p.Name == pkg {
-			for _, s := range p.Symbols {
-				if s.Name == symbol {
-					return &s
-				}
-			}
+// lookupPkgs returns the packages of pkgs named name. Several packages can
+// share the same name, like gno.land/p/demo/users and gno.land/r/demo/users.
+func lookupPkgs(pkgs []gno.Package, name string) []*gno.Package {
+	var found []*gno.Package
+	for i := range pkgs {
+		if pkgs[i].Name == name {
+			found = append(found, &pkgs[i])
 		}
 	}
-	return nil
-}
-
-func lookupSymbolByImports(symbol string, imports []*ast.ImportSpec) *gno.Symbol {
-	for _, spec := range imports {
-		value := spec.Path.Value
-
-		value = value[1 : len(value)-1]                 // remove quotes
-		value = value[strings.LastIndex(value, "/")+1:] // get last part
-
-		s := lookupSymbol(value, symbol)
-		if s != nil {
-			return s
-		}
-	}
-
-	return nil
-}
-
-func lookupPkg(pkgs []gno.Package, pkg string) *gno.Package {
-	for _, p := range pkgs {
-		if p.Name == pkg {
-			return &p
-		}
-	}
-	return nil
+	return found
 }
 
 func symbolToKind(symbol string) protocol.CompletionItemKind {
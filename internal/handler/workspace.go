@@ -0,0 +1,364 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+	"golang.org/x/mod/modfile"
+
+	"github.com/jdkato/gnols/internal/gno"
+)
+
+// workspace is a folder of the client's workspace. Each folder has its own
+// package index and gno tooling.
+type workspace struct {
+	// folder contains the path of the project.
+	folder string
+	// currentPkg contains the folder's symbols
+	currentPkg gno.Package
+	// subPkgs contains sub packages' symbols
+	subPkgs []gno.Package
+	// index is the symbol index of the folder, nil until it's loaded.
+	index *gno.Index
+	// cache is the on-disk cache of the index, it can be nil.
+	cache      *gno.SymbolCache
+	binManager *gno.BinManager
+	// checkers contains the type checkers of the folder, indexed by module
+	// directory. They keep the checked imports between the requests, until
+	// the indexes change.
+	checkers   map[string]*gno.Checker
+	checkersMu sync.Mutex
+	// modFiles contains the closest gno.mod files of the directories of the
+	// folder, indexed by directory. Like checkers, they're dropped when the
+	// indexes change.
+	modFiles   map[string]modFile
+	modFilesMu sync.Mutex
+}
+
+// modFile is the result of gno.FindModFile.
+type modFile struct {
+	mod *modfile.File
+	dir string
+	ok  bool
+}
+
+// binSettings are the settings of the BinManagers, received with
+// `workspace/didChangeConfiguration`.
+type binSettings struct {
+	gno, gnokey, gopls, root string
+	transpile, build         bool
+}
+
+// newBinManager returns the BinManager of the folder.
+func (s *binSettings) newBinManager(folder string) (*gno.BinManager, error) {
+	return gno.NewBinManager(folder, s.gno, s.gnokey, s.gopls, s.root, s.transpile, s.build)
+}
+
+// loadSymbols indexes the packages of the folder, if it's not done yet.
+func (ws *workspace) loadSymbols() error {
+	if ws.index != nil {
+		return nil
+	}
+	index := gno.NewIndex(ws.folder, ws.folder, ws.cache)
+	if err := index.Load(); err != nil {
+		return fmt.Errorf("loadSymbols: %w", err)
+	}
+	ws.index = index
+	ws.refreshPkgs()
+	return nil
+}
+
+// updateSymbols refreshes the index of the folder after the creation, the
+// change or the deletion of paths.
+func (ws *workspace) updateSymbols(paths ...string) error {
+	if ws.index == nil {
+		return ws.loadSymbols()
+	}
+	for _, path := range paths {
+		if err := ws.index.Update(path); err != nil {
+			return fmt.Errorf("updateSymbols: %w", err)
+		}
+	}
+	ws.refreshPkgs()
+	return nil
+}
+
+// refreshPkgs sets the packages of the folder from its index.
+func (ws *workspace) refreshPkgs() {
+	ws.currentPkg, ws.subPkgs = gno.Package{}, nil
+	pkgs := ws.index.Packages()
+	for _, pkg := range pkgs {
+		if pkg.Dir == ws.folder {
+			ws.currentPkg = pkg
+		} else {
+			ws.subPkgs = append(ws.subPkgs, pkg)
+		}
+	}
+	slog.Info("update workspace packages", "folder", ws.folder, "packages", len(pkgs))
+}
+
+// workspaceFolders returns the folders of params: the workspace folders, or
+// the root for the clients which don't support them.
+func workspaceFolders(params protocol.InitializeParams) []string {
+	var folders []string
+	for _, f := range params.WorkspaceFolders {
+		folders = append(folders, uri.URI(f.URI).Filename())
+	}
+	if len(folders) == 0 && params.RootURI != "" { //nolint:staticcheck
+		folders = append(folders, params.RootURI.Filename()) //nolint:staticcheck
+	}
+	return folders
+}
+
+// addWorkspace adds folder to the workspace folders. Its BinManager is created
+// if the configuration is already loaded.
+func (h *handler) addWorkspace(folder string) error {
+	h.workspacesMu.Lock()
+	defer h.workspacesMu.Unlock()
+	for _, ws := range h.workspaces {
+		if ws.folder == folder {
+			return nil
+		}
+	}
+	ws := &workspace{folder: folder, cache: h.symbolCache}
+	if h.settings != nil {
+		var err error
+		ws.binManager, err = h.settings.newBinManager(folder)
+		if err != nil {
+			return err
+		}
+	}
+	h.workspaces = append(h.workspaces, ws)
+	slog.Info("workspace folder added", "folder", folder)
+	return nil
+}
+
+// setBinManagers creates the BinManagers of the workspace folders, after a
+// configuration change.
+func (h *handler) setBinManagers() error {
+	h.workspacesMu.Lock()
+	defer h.workspacesMu.Unlock()
+	for _, ws := range h.workspaces {
+		var err error
+		ws.binManager, err = h.settings.newBinManager(ws.folder)
+		if err != nil {
+			return err
+		}
+		slog.Info("binManager created", "workspaceFolder", ws.folder)
+	}
+	return nil
+}
+
+// removeWorkspace removes folder from the workspace folders.
+func (h *handler) removeWorkspace(folder string) {
+	h.workspacesMu.Lock()
+	defer h.workspacesMu.Unlock()
+	for i, ws := range h.workspaces {
+		if ws.folder == folder {
+			h.workspaces = append(h.workspaces[:i], h.workspaces[i+1:]...)
+			slog.Info("workspace folder removed", "folder", folder)
+			return
+		}
+	}
+}
+
+// workspaceList returns a copy of the workspace folders, which can be iterated
+// while folders are added or removed.
+func (h *handler) workspaceList() []*workspace {
+	h.workspacesMu.RLock()
+	defer h.workspacesMu.RUnlock()
+	return slices.Clone(h.workspaces)
+}
+
+// workspaceOf returns the workspace folder containing path, the innermost one
+// if folders are nested. If path isn't in a workspace folder, an empty
+// workspace without BinManager is returned.
+func (h *handler) workspaceOf(path string) *workspace {
+	h.workspacesMu.RLock()
+	defer h.workspacesMu.RUnlock()
+	var found *workspace
+	for _, ws := range h.workspaces {
+		if !inDir(ws.folder, path) {
+			continue
+		}
+		if found == nil || len(ws.folder) > len(found.folder) {
+			found = ws
+		}
+	}
+	if found == nil {
+		return &workspace{}
+	}
+	return found
+}
+
+// inDir returns true if path is dir or is inside dir.
+func inDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// checker returns the type checker of the package in dir, whose files differ
+// from the disk as described by overlay. The checker of the module containing
+// dir is reused if there's one.
+func (h *handler) checker(dir string, overlay map[string]string) *gno.Checker {
+	ws := h.workspaceOf(dir)
+	_, modDir, _ := h.findModFile(dir)
+	ws.checkersMu.Lock()
+	defer ws.checkersMu.Unlock()
+	if chk, ok := ws.checkers[modDir]; ok {
+		chk.Update(overlay)
+		return chk
+	}
+	chk := gno.NewChecker(h.importResolver(dir), overlay)
+	if ws.checkers == nil {
+		ws.checkers = make(map[string]*gno.Checker)
+	}
+	ws.checkers[modDir] = chk
+	return chk
+}
+
+// findModFile is like gno.FindModFile, but the result is cached per
+// directory, since walking up to the root is done on each resolved import.
+func (h *handler) findModFile(dir string) (*modfile.File, string, bool) {
+	ws := h.workspaceOf(dir)
+	ws.modFilesMu.Lock()
+	defer ws.modFilesMu.Unlock()
+	if f, ok := ws.modFiles[dir]; ok {
+		return f.mod, f.dir, f.ok
+	}
+	mod, modDir, ok := gno.FindModFile(dir)
+	if ws.modFiles == nil {
+		ws.modFiles = make(map[string]modFile)
+	}
+	ws.modFiles[dir] = modFile{mod: mod, dir: modDir, ok: ok}
+	return mod, modDir, ok
+}
+
+// resetCheckers drops the type checkers and the cached gno.mod files, after a
+// change of the files or of the indexes which may change the checked imports.
+func (h *handler) resetCheckers() {
+	for _, ws := range h.workspaceList() {
+		ws.checkersMu.Lock()
+		ws.checkers = nil
+		ws.checkersMu.Unlock()
+		ws.modFilesMu.Lock()
+		ws.modFiles = nil
+		ws.modFilesMu.Unlock()
+	}
+}
+
+// workspacePkgs returns the indexed packages of all the workspace folders.
+func (h *handler) workspacePkgs() []gno.Package {
+	var pkgs []gno.Package
+	for _, ws := range h.workspaceList() {
+		if ws.currentPkg.Dir != "" {
+			pkgs = append(pkgs, ws.currentPkg)
+		}
+		pkgs = append(pkgs, ws.subPkgs...)
+	}
+	return pkgs
+}
+
+// loadSymbols indexes the packages of the workspace folders not indexed yet.
+func (h *handler) loadSymbols() error {
+	for _, ws := range h.workspaceList() {
+		if err := ws.loadSymbols(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateSymbols refreshes the index of the workspace folders containing paths,
+// after their creation, change or deletion. The packages of each folder are
+// refreshed once for all the paths.
+func (h *handler) updateSymbols(paths ...string) error {
+	h.resetCheckers()
+	for _, ws := range h.workspaceList() {
+		var inWs []string
+		for _, path := range paths {
+			if inDir(ws.folder, path) {
+				inWs = append(inWs, path)
+			}
+		}
+		if len(inWs) == 0 {
+			continue
+		}
+		if err := ws.updateSymbols(inWs...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *handler) handleDidChangeWorkspaceFolders(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params protocol.DidChangeWorkspaceFoldersParams
+	if err := readParams(req, &params); err != nil {
+		return replyErr(ctx, reply, err)
+	}
+	h.resetCheckers()
+	for _, f := range params.Event.Removed {
+		h.removeWorkspace(uri.URI(f.URI).Filename())
+	}
+	for _, f := range params.Event.Added {
+		folder := uri.URI(f.URI).Filename()
+		if err := h.addWorkspace(folder); err != nil {
+			return replyErr(ctx, reply, err)
+		}
+		if err := h.workspaceOf(folder).loadSymbols(); err != nil {
+			return replyErr(ctx, reply, err)
+		}
+	}
+	return reply(ctx, nil, nil)
+}
+
+// registerFileWatchers asks the client to send `workspace/didChangeWatchedFiles`
+// notifications for the gno and gno.mod files, to keep the indexes up to date.
+func (h *handler) registerFileWatchers() {
+	if !h.watchFiles {
+		return
+	}
+	params := protocol.RegistrationParams{
+		Registrations: []protocol.Registration{{
+			ID:     "gnols.watchFiles",
+			Method: protocol.MethodWorkspaceDidChangeWatchedFiles,
+			RegisterOptions: protocol.DidChangeWatchedFilesRegistrationOptions{
+				Watchers: []protocol.FileSystemWatcher{
+					{GlobPattern: "**/*.gno"},
+					{GlobPattern: "**/" + gno.ModFileName},
+				},
+			},
+		}},
+	}
+	go func() {
+		_, err := h.connPool.Call(context.Background(), protocol.MethodClientRegisterCapability, params, nil)
+		if err != nil {
+			slog.Error("register file watchers", "err", err)
+		}
+	}()
+}
+
+func (h *handler) handleDidChangeWatchedFiles(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params protocol.DidChangeWatchedFilesParams
+	if err := readParams(req, &params); err != nil {
+		return replyErr(ctx, reply, err)
+	}
+	paths := make([]string, 0, len(params.Changes))
+	for _, change := range params.Changes {
+		path := change.URI.Filename()
+		slog.Info("watched file changed", "path", path, "type", change.Type)
+		paths = append(paths, path)
+	}
+	if err := h.updateSymbols(paths...); err != nil {
+		return replyErr(ctx, reply, err)
+	}
+	return reply(ctx, nil, nil)
+}
@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+
+	"github.com/jdkato/gnols/internal/gno"
+	"github.com/jdkato/gnols/internal/store"
+)
+
+func TestWorkspaceOf(t *testing.T) {
+	h := &handler{workspaces: []*workspace{
+		{folder: "/src/a"},
+		{folder: "/src/b"},
+		{folder: "/src/b/nested"},
+	}}
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/src/a/x.gno", want: "/src/a"},
+		{path: "/src/b", want: "/src/b"},
+		{path: "/src/b/sub/x.gno", want: "/src/b"},
+		{path: "/src/b/nested/x.gno", want: "/src/b/nested"},
+		{path: "/src/bb/x.gno", want: ""},
+	}
+	for _, tt := range tests {
+		if got := h.workspaceOf(tt.path).folder; got != tt.want {
+			t.Errorf("workspaceOf(%q): want %q, got %q", tt.path, tt.want, got)
+		}
+	}
+}
+
+func TestWorkspaceFolders(t *testing.T) {
+	tests := []struct {
+		name   string
+		params protocol.InitializeParams
+		want   []string
+	}{
+		{
+			name: "folders",
+			params: protocol.InitializeParams{
+				RootURI:          uri.File("/src"),
+				WorkspaceFolders: []protocol.WorkspaceFolder{{URI: string(uri.File("/src/a"))}},
+			},
+			want: []string{"/src/a"},
+		},
+		{
+			name:   "root",
+			params: protocol.InitializeParams{RootURI: uri.File("/src")},
+			want:   []string{"/src"},
+		},
+		{
+			name: "none",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := workspaceFolders(tt.params); !slices.Equal(got, tt.want) {
+				t.Errorf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGetBinManagerNoWorkspace(t *testing.T) {
+	h := &handler{configLoaded: make(chan struct{})}
+	close(h.configLoaded)
+	h.workspaces = []*workspace{{folder: "/src/a"}}
+	h.removeWorkspace("/src/a")
+
+	if _, err := h.getBinManager("/src/a/x.gno"); !errors.Is(err, ErrNoWorkspace) {
+		t.Errorf("want %v, got %v", ErrNoWorkspace, err)
+	}
+	diags, err := h.getDiagnostics(&store.Document{Path: "/src/a/x.gno"})
+	if err != nil || len(diags) != 0 {
+		t.Errorf("want no diagnostics, got %v, %v", diags, err)
+	}
+}
+
+func TestGetBinManagerOutsideWorkspace(t *testing.T) {
+	h := &handler{configLoaded: make(chan struct{})}
+	close(h.configLoaded)
+	h.workspaces = []*workspace{{folder: "/src/a", binManager: &gno.BinManager{}}}
+
+	if _, err := h.getBinManager("/src/a/x.gno"); err != nil {
+		t.Errorf("want no error, got %v", err)
+	}
+	if _, err := h.getBinManager("/src/b/x.gno"); !errors.Is(err, ErrNoWorkspace) {
+		t.Errorf("want %v, got %v", ErrNoWorkspace, err)
+	}
+}
+
+func TestFindModFileCache(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	if err := os.MkdirAll(sub, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	modPath := filepath.Join(dir, "gno.mod")
+	if err := os.WriteFile(modPath, []byte("module gno.land/r/demo/foo\n"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	h := &handler{workspaces: []*workspace{{folder: dir}}}
+
+	if _, modDir, ok := h.findModFile(sub); !ok || modDir != dir {
+		t.Fatalf("want %q, got %q %v", dir, modDir, ok)
+	}
+	// the removal isn't seen until the cache is dropped
+	if err := os.Remove(modPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, modDir, ok := h.findModFile(sub); !ok || modDir != dir {
+		t.Errorf("want cached %q, got %q %v", dir, modDir, ok)
+	}
+	h.resetCheckers()
+	if _, modDir, ok := h.findModFile(sub); ok {
+		t.Errorf("want no gno.mod, got %q", modDir)
+	}
+}
+
+func TestUpdateSymbolsPaths(t *testing.T) {
+	var (
+		dirA = t.TempDir()
+		dirB = t.TempDir()
+		h    = &handler{workspaces: []*workspace{{folder: dirA}, {folder: dirB}}}
+	)
+	if err := h.loadSymbols(); err != nil {
+		t.Fatal(err)
+	}
+	// writeFile creates the package name in dir.
+	writeFile := func(dir, name string) string {
+		path := filepath.Join(dir, name, name+".gno")
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("package "+name+"\n\nfunc F() {}\n"), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	paths := []string{
+		writeFile(dirA, "foo"),
+		writeFile(dirA, "bar"),
+		writeFile(dirB, "baz"),
+		filepath.Join(t.TempDir(), "outside.gno"),
+	}
+
+	if err := h.updateSymbols(paths...); err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range [][]string{{"bar", "foo"}, {"baz"}} {
+		var got []string
+		for _, pkg := range h.workspaces[i].subPkgs {
+			got = append(got, pkg.Name)
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, want) {
+			t.Errorf("workspace %d: want %q, got %q", i, want, got)
+		}
+	}
+}
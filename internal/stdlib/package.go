@@ -1,9 +1,8 @@
 package stdlib
 
 import (
-	"bytes"
-	_ "embed"
-	"encoding/gob"
+	"embed"
+	"log/slog"
 
 	"github.com/jdkato/gnols/internal/gno"
 )
@@ -11,6 +10,9 @@ import (
 //go:embed stdlib.gob
 var encoded []byte
 
+//go:embed versions
+var versions embed.FS
+
 // Packages is a list of all the packages in the Gno standard library.
 //
 // We use it to power live, in-editor intelisense such as:
@@ -21,9 +23,51 @@ var encoded []byte
 // The list is generated by the `/cmd/gen` command.
 var Packages = []gno.Package{}
 
+// Version is the gno version Packages was generated from, empty if it's
+// unknown.
+var Version string
+
+// Schema is the schema of the index of Packages. If it's older than
+// gno.SchemaVersion, the index lacks the symbol information added since then,
+// and must be generated again.
+var Schema int
+
 func init() {
-	dec := gob.NewDecoder(bytes.NewReader(encoded))
-	if err := dec.Decode(&Packages); err != nil {
-		panic(err)
+	f := decode(encoded)
+	Packages, Version, Schema = f.Packages, f.Version, f.Schema
+}
+
+// decode returns the index encoded in data. An index which can't be decoded
+// is logged and replaced by an empty one, so the server still starts without
+// the stdlib symbols.
+func decode(data []byte) *gno.IndexFile {
+	f, err := gno.DecodeIndex(data)
+	if err != nil {
+		slog.Error("invalid stdlib index, generate it again with cmd/gen", "err", err)
+		return &gno.IndexFile{}
+	}
+	return f
+}
+
+// Lookup returns the packages of the index generated from the gno version, or
+// false if there's none.
+func Lookup(version string) ([]gno.Package, bool) {
+	if version == "" {
+		return nil, false
+	}
+	if version == Version {
+		return Packages, true
+	}
+	for _, ext := range []string{".gob", ".json"} {
+		bz, err := versions.ReadFile("versions/" + gno.IndexName(version) + ext)
+		if err != nil {
+			continue
+		}
+		f, err := gno.DecodeIndex(bz)
+		if err != nil {
+			return nil, false
+		}
+		return f.Packages, true
 	}
+	return nil, false
 }
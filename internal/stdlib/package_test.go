@@ -0,0 +1,24 @@
+package stdlib
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/jdkato/gnols/internal/gno"
+)
+
+func TestDecode(t *testing.T) {
+	var buf bytes.Buffer
+	index := gno.NewIndexFile("v1", []gno.Package{{Name: "strings", ImportPath: "strings"}})
+	if err := index.WriteGob(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if f := decode(buf.Bytes()); f.Version != "v1" || len(f.Packages) != 1 {
+		t.Errorf("want %v, got %v", index, f)
+	}
+
+	// an invalid index is replaced by an empty one
+	if f := decode([]byte("not an index")); f.Version != "" || len(f.Packages) != 0 || f.Schema != 0 {
+		t.Errorf("want an empty index, got %v", f)
+	}
+}
@@ -32,8 +32,13 @@ func NewParsedGnoFile(path, content string) *ParsedGnoFile {
 	return &ParsedGnoFile{File: file, FileSet: fset, Errors: parseErr}
 }
 
-// ApplyChangesToAst applies the changes in the Document to the AST.
+// ApplyChangesToAst applies the changes in the Document to the AST, or to the
+// parsed gno.mod file.
 func (d *Document) ApplyChangesToAst(path, content string) {
+	if IsModFile(path) {
+		d.Mod = NewParsedModFile(path, content)
+		return
+	}
 	d.Pgf = NewParsedGnoFile(path, content)
 }
 
@@ -2,19 +2,22 @@ package store
 
 import (
 	"errors"
+	"go/token"
 	"strings"
-	"unicode/utf8"
 
 	"go.lsp.dev/protocol"
 )
 
-// Document represents an opened Gno file.
+// Document represents an opened Gno file, or gno.mod file.
 type Document struct {
 	URI     protocol.DocumentURI
 	Path    string
 	Content string
 	Lines   []string
-	Pgf     *ParsedGnoFile
+	// Pgf is nil for a gno.mod file.
+	Pgf *ParsedGnoFile
+	// Mod is the parsed gno.mod file, nil for a Gno file.
+	Mod *ParsedModFile
 }
 
 type HoveredToken struct {
@@ -29,37 +32,65 @@ func (d *Document) ApplyChanges(changes []protocol.TextDocumentContentChangeEven
 	d.ApplyChangesToAst(d.Path, d.Content)
 }
 
-func (d *Document) SpanToRange(start, _ int) protocol.Range {
-	line := 0
+// RangeOf converts the start and end positions of a node of d.Pgf to a LSP
+// range.
+func (d *Document) RangeOf(start, end token.Pos) protocol.Range {
+	return protocol.Range{
+		Start: d.PosToPosition(start),
+		End:   d.PosToPosition(end),
+	}
+}
 
-	offset := 0
-	for i, l := range d.Lines {
-		if offset+len(l) > start {
-			line = i
-			break
-		}
-		offset += len(l)
+// PosToPosition converts pos, a position in d.Pgf, to a LSP position.
+//
+// The token.FileSet columns are byte offsets, while LSP characters are
+// counted in UTF-16 code units, so the line content is used to convert them.
+func (d *Document) PosToPosition(pos token.Pos) protocol.Position {
+	p := d.Pgf.FileSet.Position(pos)
+	if !p.IsValid() {
+		return protocol.Position{}
+	}
+	var (
+		line = p.Line - 1
+		char = p.Column - 1
+	)
+	if line < len(d.Lines) && char <= len(d.Lines[line]) {
+		char = utf16Len(d.Lines[line][:char])
 	}
+	return protocol.Position{
+		Line:      uint32(line),
+		Character: uint32(char),
+	}
+}
 
-	return protocol.Range{
-		Start: protocol.Position{
-			Line:      uint32(line),
-			Character: 1,
-		},
-		End: protocol.Position{
-			Line:      uint32(line),
-			Character: 1,
-		},
+// OffsetToPosition converts a byte offset in d.Content to a LSP position.
+func (d *Document) OffsetToPosition(offset int) protocol.Position {
+	var line int
+	for ; line < len(d.Lines) && offset > len(d.Lines[line]); line++ {
+		offset -= len(d.Lines[line])
+	}
+	if line < len(d.Lines) && offset == len(d.Lines[line]) && strings.HasSuffix(d.Lines[line], "\n") {
+		// offset is right after the newline
+		line, offset = line+1, 0
+	}
+	char := offset
+	if line < len(d.Lines) {
+		char = utf16Len(d.Lines[line][:offset])
+	}
+	return protocol.Position{
+		Line:      uint32(line),
+		Character: uint32(char),
 	}
 }
 
+// PositionToOffset converts a LSP position to a byte offset in d.Content.
 func (d *Document) PositionToOffset(pos protocol.Position) int {
 	offset := 0
 	for i, l := range d.Lines {
 		if i == int(pos.Line) {
-			break
+			return offset + utf16Offset(l, int(pos.Character))
 		}
-		offset += utf8.RuneCountInString(l)
+		offset += len(l)
 	}
 	return offset + int(pos.Character)
 }
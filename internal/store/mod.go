@@ -0,0 +1,37 @@
+package store
+
+import (
+	"errors"
+	"path/filepath"
+
+	"golang.org/x/mod/modfile"
+
+	"github.com/jdkato/gnols/internal/gno"
+)
+
+// A ParsedModFile contains the results of parsing a gno.mod file.
+type ParsedModFile struct {
+	// File is nil only if the file is too malformed to be parsed leniently.
+	File   *modfile.File
+	Errors modfile.ErrorList
+}
+
+// NewParsedModFile parses the gno.mod file. If it contains errors, it's
+// parsed again leniently so File contains the valid directives.
+func NewParsedModFile(path, content string) *ParsedModFile {
+	file, err := modfile.Parse(path, []byte(content), nil)
+	if err == nil {
+		return &ParsedModFile{File: file}
+	}
+	var errs modfile.ErrorList
+	if !errors.As(err, &errs) {
+		errs = modfile.ErrorList{{Filename: path, Err: err}}
+	}
+	file, _ = modfile.ParseLax(path, []byte(content), nil)
+	return &ParsedModFile{File: file, Errors: errs}
+}
+
+// IsModFile returns true if path is a gno.mod file.
+func IsModFile(path string) bool {
+	return filepath.Base(path) == gno.ModFileName
+}
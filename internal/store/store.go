@@ -26,15 +26,13 @@ func (s *DocumentStore) Save(uri uri.URI, content string) (*Document, error) {
 	if err != nil {
 		return nil, fmt.Errorf("normalize path %s: %w", uri, err)
 	}
-	pgf := NewParsedGnoFile(path, content)
-
 	doc := &Document{
 		URI:     uri,
 		Path:    path,
 		Content: content,
 		Lines:   strings.SplitAfter(content, "\n"),
-		Pgf:     pgf,
 	}
+	doc.ApplyChangesToAst(path, content)
 	s.documents.Set(path, doc)
 	return doc, nil
 }
@@ -41,3 +41,32 @@ func canonical(path string) (string, error) {
 
 	return resolvedPath, nil
 }
+
+// utf16Len returns the length of s in UTF-16 code units, which is how LSP
+// positions count characters.
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// utf16Offset returns the byte offset in s of the n-th UTF-16 code unit.
+func utf16Offset(s string, n int) int {
+	for i, r := range s {
+		if n <= 0 {
+			return i
+		}
+		if r >= 0x10000 {
+			n -= 2
+		} else {
+			n--
+		}
+	}
+	return len(s)
+}